@@ -16,4 +16,7 @@ func main() {
 	}
 
 	plugin.Serve(&opts)
+
+	// Serve returns once terraform is done with the provider
+	vra7.Logout()
 }
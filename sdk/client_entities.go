@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"time"
@@ -21,18 +22,8 @@ type APIRequest struct {
 	URL     string
 	Headers map[string]string
 	Body    io.Reader
-}
-
-// APIError represents an error from the vRA API.
-type APIError struct {
-	Errors []Error `json:"errors"`
-}
 
-//Error API Error
-type Error struct {
-	Code          int    `json:"code"`
-	Message       string `json:"message"`
-	SystemMessage string `json:"systemMessage"`
+	ctx context.Context
 }
 
 //AuthResponse - This struct contains response of user authentication call.
@@ -1,11 +1,12 @@
 package sdk
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	logging "github.com/op/go-logging"
 	"github.com/vmware/terraform-provider-vra7/utils"
@@ -28,17 +29,40 @@ const (
 	PATCH               = "PATCH"
 	PUT                 = "PUT"
 	DELETE              = "DELETE"
+	HEAD                = "HEAD"
+
+	// TokenExpiryMargin is how long before its expiry a cached bearer token is renewed
+	TokenExpiryMargin = 5 * time.Minute
 )
 
 // APIClient represents the vra http client used throughout this provider
 type APIClient struct {
-	Username    string
-	Password    string
-	BaseURL     string
-	Tenant      string
-	Insecure    bool
-	BearerToken string
-	Client      *http.Client
+	Username     string
+	Password     string
+	BaseURL      string
+	Tenant       string
+	Insecure     bool
+	BearerToken  string
+	TokenExpires time.Time
+	// Client is the net/http client of the NetHTTPClient of a new APIClient, the transport
+	// settings only apply to it
+	Client *http.Client
+	// HTTPClient sends the requests to the server
+	HTTPClient  HTTPClient
+	RetryPolicy RetryPolicy
+	// Trace logs the requests and responses, with their secrets redacted, at debug level
+	Trace bool
+	// Version is the version of the vRA server, set by DetectVersion, zero if unknown
+	Version Version
+
+	tokenLock sync.Mutex
+	// preIssuedToken is true if the bearer token was given to UseToken, it cannot be renewed
+	preIssuedToken bool
+
+	// lookups caches the catalog items, request templates and business groups
+	lookups lookupCache
+	// tenants are the clients of the other tenants created by ForTenant
+	tenants tenantClients
 }
 
 // AddHeader adds headers to the request
@@ -49,6 +73,24 @@ func (ar *APIRequest) AddHeader(key, val string) {
 	ar.Headers[key] = val
 }
 
+// Context returns the context of the request, which is context.Background
+// unless it was changed with WithContext
+func (ar *APIRequest) Context() context.Context {
+	if ar.ctx != nil {
+		return ar.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of the request with its context set to ctx.
+// The request is aborted when ctx is cancelled.
+func (ar *APIRequest) WithContext(ctx context.Context) *APIRequest {
+	r := new(APIRequest)
+	*r = *ar
+	r.ctx = ctx
+	return r
+}
+
 //ContentType returns the content type set in the request header
 func (ar *APIRequest) ContentType() string {
 	if ar.Headers == nil {
@@ -69,31 +111,9 @@ func (ar *APIRequest) CopyHeadersTo(req *http.Request) {
 	}
 }
 
-// Error Implement Go error interface for ApiError
-func (e APIError) Error() string {
-	return fmt.Sprintf("vRealize API: %+v", e.Errors)
-}
-
-// GetAPIError reads an error out of the HTTP response, or does nothing if
-// no error occured.
-func GetAPIError(respBody []byte, statusCode int) error {
-	apiError := APIError{}
-	e := Error{}
-	unmarshalErr := json.Unmarshal(respBody, &apiError)
-	if unmarshalErr != nil {
-		// Do not return this error just log it.
-		log.Error("Error is %v ", unmarshalErr)
-		e.Message = string(respBody)
-	}
-
-	e.Code = statusCode
-	apiError.Errors = append(apiError.Errors, e)
-	return apiError
-}
-
 // FromAPIRequestToHTTPRequest converts API request object to http request
 func FromAPIRequestToHTTPRequest(apiReq *APIRequest) (*http.Request, error) {
-	req, err := http.NewRequest(apiReq.Method, apiReq.URL, apiReq.Body)
+	req, err := http.NewRequestWithContext(apiReq.Context(), apiReq.Method, apiReq.URL, apiReq.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -107,12 +127,25 @@ func FromAPIRequestToHTTPRequest(apiReq *APIRequest) (*http.Request, error) {
 
 // FromHTTPRespToAPIResp converts Http response to API response
 func FromHTTPRespToAPIResp(resp *http.Response) (*APIResponse, error) {
-	respBody, err := ioutil.ReadAll(resp.Body)
+	apiResp, err := readHTTPResponse(resp)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode/100 != 2 {
-		return nil, GetAPIError(respBody, resp.StatusCode)
+	var method, url string
+	if resp.Request != nil {
+		method, url = resp.Request.Method, resp.Request.URL.String()
+	}
+	if apiErr := checkAPIResponse(method, url, apiResp); apiErr != nil {
+		return nil, apiErr
+	}
+	return apiResp, nil
+}
+
+// readHTTPResponse reads the http response into an API response, whatever its status code is
+func readHTTPResponse(resp *http.Response) (*APIResponse, error) {
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
 
 	apiResp := &APIResponse{}
@@ -127,6 +160,18 @@ func FromHTTPRespToAPIResp(resp *http.Response) (*APIResponse, error) {
 	return apiResp, nil
 }
 
+// checkAPIResponse returns the API error of the response to the request sent with the given
+// method and url, or nil if its status code is a success
+func checkAPIResponse(method, url string, resp *APIResponse) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	apiErr := NewAPIError(resp.Body, resp.StatusCode)
+	apiErr.Method = method
+	apiErr.URL = url
+	return apiErr
+}
+
 // BuildEncodedURL build the url by adding the base url and headers, etc
 func (c *APIClient) BuildEncodedURL(relativePath string, queryParameters map[string]string) string {
 	//Todo it might be better to swith to Viper to load all the config at once
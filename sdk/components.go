@@ -0,0 +1,266 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TemplateComponent - a component of the blueprint in a catalog item request template, like a machine,
+// or an entry of a nested collection of a component, like a disk or a network card
+type TemplateComponent struct {
+	ComponentTypeID string
+	ComponentID     *string
+	ClassID         string
+	TypeFilter      *string
+	// Properties are the data of the component, but for its nested collections
+	Properties map[string]interface{}
+	// Children are the nested collections of the component, like disks and nics, by name
+	Children map[string][]*TemplateComponent
+
+	// unknown are the fields of the component which are not modelled, they are
+	// serialized back as they are
+	unknown map[string]json.RawMessage
+	// present are the fields the parsed json had, the modelled ones are serialized
+	// back even when they are empty
+	present map[string]bool
+}
+
+// component fields
+const (
+	componentTypeIDField = "componentTypeId"
+	componentIDField     = "componentId"
+	classIDField         = "classId"
+	typeFilterField      = "typeFilter"
+	componentDataField   = "data"
+)
+
+// UnmarshalJSON parses a component out of the json of a request template
+func (c *TemplateComponent) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*c = TemplateComponent{
+		Properties: make(map[string]interface{}),
+		Children:   make(map[string][]*TemplateComponent),
+		unknown:    make(map[string]json.RawMessage),
+		present:    make(map[string]bool),
+	}
+	for key, value := range fields {
+		var err error
+		c.present[key] = true
+		switch key {
+		case componentTypeIDField:
+			err = json.Unmarshal(value, &c.ComponentTypeID)
+		case componentIDField:
+			err = json.Unmarshal(value, &c.ComponentID)
+		case classIDField:
+			err = json.Unmarshal(value, &c.ClassID)
+		case typeFilterField:
+			err = json.Unmarshal(value, &c.TypeFilter)
+		case componentDataField:
+			err = c.unmarshalData(value)
+		default:
+			c.unknown[key] = value
+		}
+		if err != nil {
+			return fmt.Errorf("Invalid %s of the component: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// unmarshalData splits the data of the component into its properties and its nested collections
+func (c *TemplateComponent) unmarshalData(data []byte) error {
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return err
+	}
+	for key, value := range properties {
+		if isComponentList(value) {
+			var children []*TemplateComponent
+			if err := json.Unmarshal(value, &children); err != nil {
+				return err
+			}
+			c.Children[key] = children
+			continue
+		}
+		var property interface{}
+		if err := json.Unmarshal(value, &property); err != nil {
+			return err
+		}
+		c.Properties[key] = property
+	}
+	return nil
+}
+
+// MarshalJSON serializes the component back to the json of a request template, with
+// the fields it does not model. A modelled field is only written if the parsed json had
+// it or if it is set, so that a component is serialized back as it was.
+func (c TemplateComponent) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(c.unknown)+5)
+	for key, value := range c.unknown {
+		fields[key] = value
+	}
+	if c.present[componentTypeIDField] || c.ComponentTypeID != "" {
+		fields[componentTypeIDField] = c.ComponentTypeID
+	}
+	if c.present[componentIDField] || c.ComponentID != nil {
+		fields[componentIDField] = c.ComponentID
+	}
+	if c.present[classIDField] || c.ClassID != "" {
+		fields[classIDField] = c.ClassID
+	}
+	if c.present[typeFilterField] || c.TypeFilter != nil {
+		fields[typeFilterField] = c.TypeFilter
+	}
+
+	if c.present[componentDataField] || len(c.Properties) > 0 || len(c.Children) > 0 {
+		data := make(map[string]interface{}, len(c.Properties)+len(c.Children))
+		for key, value := range c.Properties {
+			data[key] = value
+		}
+		for key, children := range c.Children {
+			data[key] = children
+		}
+		fields[componentDataField] = data
+	}
+	return json.Marshal(fields)
+}
+
+// Property returns the value of a property of the component. The properties of the entries
+// of the nested collections are named <collection>.<index>.<property>, like disks.0.capacity.
+func (c *TemplateComponent) Property(name string) (interface{}, bool) {
+	if value, ok := c.Properties[name]; ok {
+		return value, true
+	}
+	child, childProperty, err := c.childOf(name)
+	if err != nil || child == nil {
+		return nil, false
+	}
+	return child.Property(childProperty)
+}
+
+// SetProperty sets the value of a property of the component, named like in Property. A
+// property which does not exist yet is added to the component.
+func (c *TemplateComponent) SetProperty(name string, value interface{}) error {
+	if _, ok := c.Properties[name]; !ok {
+		child, childProperty, err := c.childOf(name)
+		if err != nil {
+			return err
+		}
+		if child != nil {
+			return child.SetProperty(childProperty, value)
+		}
+	}
+	if c.Properties == nil {
+		c.Properties = make(map[string]interface{})
+	}
+	c.Properties[name] = value
+	return nil
+}
+
+// childOf returns the entry of a nested collection a property name of the form
+// <collection>.<index>.<property> refers to, and the name of the property of the entry.
+// The entry is nil if the name does not refer to a nested collection.
+func (c *TemplateComponent) childOf(name string) (*TemplateComponent, string, error) {
+	parts := strings.SplitN(name, ".", 3)
+	if len(parts) != 3 {
+		return nil, "", nil
+	}
+	children, ok := c.Children[parts[0]]
+	if !ok {
+		return nil, "", nil
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, "", nil
+	}
+	if index < 0 || index >= len(children) {
+		return nil, "", fmt.Errorf("The component has no %s %d, it has %d", parts[0], index, len(children))
+	}
+	return children[index], parts[2], nil
+}
+
+// Components parses the components of the blueprint out of the data of the request template,
+// by name. The other fields of the data are the fields of the deployment, like _leaseDays.
+func (t *CatalogItemRequestTemplate) Components() (map[string]*TemplateComponent, error) {
+	components := make(map[string]*TemplateComponent)
+	for name, value := range t.Data {
+		if !isComponent(value) {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		var component TemplateComponent
+		if err := json.Unmarshal(data, &component); err != nil {
+			return nil, fmt.Errorf("Invalid component %s in the request template: %v", name, err)
+		}
+		components[name] = &component
+	}
+	return components, nil
+}
+
+// ComponentNames returns the names of the components of the blueprint in the request template
+func (t *CatalogItemRequestTemplate) ComponentNames() []string {
+	var names []string
+	for name, value := range t.Data {
+		if isComponent(value) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetComponent writes the component back into the data of the request template
+func (t *CatalogItemRequestTemplate) SetComponent(name string, component *TemplateComponent) error {
+	data, err := json.Marshal(component)
+	if err != nil {
+		return err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if t.Data == nil {
+		t.Data = make(map[string]interface{})
+	}
+	t.Data[name] = value
+	return nil
+}
+
+// isComponent returns true if the value of a field of the data of a request template
+// is a component, rather than a field of the deployment
+func isComponent(value interface{}) bool {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasType := fields[componentTypeIDField]
+	_, hasClass := fields[classIDField]
+	return hasType || hasClass
+}
+
+// isComponentList returns true if the json value is a non empty list of components
+func isComponentList(value json.RawMessage) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+		return false
+	}
+	var entries []interface{}
+	if err := json.Unmarshal(value, &entries); err != nil || len(entries) == 0 {
+		return false
+	}
+	for _, entry := range entries {
+		if !isComponent(entry) {
+			return false
+		}
+	}
+	return true
+}
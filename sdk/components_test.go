@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+func TestRequestTemplateComponents(t *testing.T) {
+	var requestTemplate CatalogItemRequestTemplate
+	err := json.Unmarshal([]byte(requestTemplateResponse), &requestTemplate)
+	utils.AssertNilError(t, err)
+
+	// the fields of the deployment are not components
+	utils.AssertEqualsInt(t, 1, len(requestTemplate.ComponentNames()))
+	utils.AssertEqualsString(t, "machine2.vsphere", requestTemplate.ComponentNames()[0])
+
+	components, err := requestTemplate.Components()
+	utils.AssertNilError(t, err)
+	machine := components["machine2.vsphere"]
+	utils.AssertNotNil(t, machine)
+	utils.AssertEqualsString(t, "com.vmware.csp.component.cafe.composition", machine.ComponentTypeID)
+	utils.AssertEqualsString(t, "Blueprint.Component.Declaration", machine.ClassID)
+	utils.AssertEqualsString(t, "Prativa_CentOs*machine2.vsphere", *machine.TypeFilter)
+	utils.AssertTrue(t, "the component id is null", machine.ComponentID == nil)
+
+	// the nested collections are typed, the empty ones are plain properties
+	utils.AssertEqualsInt(t, 1, len(machine.Children))
+	disks := machine.Children["disks"]
+	utils.AssertEqualsInt(t, 1, len(disks))
+	utils.AssertEqualsString(t, "Infrastructure.Compute.Machine.MachineDisk", disks[0].ClassID)
+	capacity, ok := machine.Property("disks.0.capacity")
+	utils.AssertTrue(t, "the disk has a capacity", ok)
+	utils.AssertTrue(t, "the disk capacity is 8", capacity == 8.0)
+	_, ok = machine.Properties["security_groups"]
+	utils.AssertTrue(t, "security_groups is a property", ok)
+	_, ok = machine.Properties["nics"]
+	utils.AssertTrue(t, "nics is a property", ok)
+
+	err = machine.SetProperty("cpu", 2)
+	utils.AssertNilError(t, err)
+	err = machine.SetProperty("disks.0.capacity", 20)
+	utils.AssertNilError(t, err)
+	err = machine.SetProperty("mock.custom.property", "value")
+	utils.AssertNilError(t, err)
+	err = machine.SetProperty("disks.1.capacity", 20)
+	utils.AssertNotNilError(t, err)
+
+	err = requestTemplate.SetComponent("machine2.vsphere", machine)
+	utils.AssertNilError(t, err)
+	data := requestTemplate.Data["machine2.vsphere"].(map[string]interface{})["data"].(map[string]interface{})
+	utils.AssertTrue(t, "the cpu is updated", data["cpu"] == 2.0)
+	utils.AssertEqualsString(t, "value", data["mock.custom.property"].(string))
+	disk := data["disks"].([]interface{})[0].(map[string]interface{})["data"].(map[string]interface{})
+	utils.AssertTrue(t, "the disk capacity is updated", disk["capacity"] == 20.0)
+}
+
+func TestTemplateComponentRoundTrip(t *testing.T) {
+	var requestTemplate CatalogItemRequestTemplate
+	err := json.Unmarshal([]byte(requestTemplateResponse), &requestTemplate)
+	utils.AssertNilError(t, err)
+	original := requestTemplate.Data["machine2.vsphere"].(map[string]interface{})
+	// a field the sdk does not know
+	original["mock.unknown.field"] = map[string]interface{}{"nested": []interface{}{1.0, "two"}}
+
+	components, err := requestTemplate.Components()
+	utils.AssertNilError(t, err)
+	serialized, err := json.Marshal(components["machine2.vsphere"])
+	utils.AssertNilError(t, err)
+	var roundTrip map[string]interface{}
+	err = json.Unmarshal(serialized, &roundTrip)
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the component is serialized back as it was", reflect.DeepEqual(original, roundTrip))
+}
+
+func TestTemplateComponentPartialRoundTrip(t *testing.T) {
+	// the entries of the nested collections, like the disks, only have some of the fields
+	// of a component, the missing ones are not added
+	for _, original := range []string{
+		`{"data":{"capacity":20,"id":1}}`,
+		`{"classId":"Infrastructure.Compute.Machine.MachineDisk","componentId":null,"data":{}}`,
+		`{"componentTypeId":"com.vmware.csp.component.cafe.composition","typeFilter":null}`,
+		`{}`,
+	} {
+		var component TemplateComponent
+		err := json.Unmarshal([]byte(original), &component)
+		utils.AssertNilError(t, err)
+		serialized, err := json.Marshal(component)
+		utils.AssertNilError(t, err)
+		var expected, roundTrip map[string]interface{}
+		json.Unmarshal([]byte(original), &expected)
+		json.Unmarshal(serialized, &roundTrip)
+		utils.AssertTrue(t, fmt.Sprintf("%s is serialized back as it was: %s", original, serialized),
+			reflect.DeepEqual(expected, roundTrip))
+	}
+
+	// the fields set on a component which was not parsed are serialized
+	serialized, err := json.Marshal(TemplateComponent{ClassID: "Infrastructure.Compute.Machine.MachineDisk"})
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, `{"classId":"Infrastructure.Compute.Machine.MachineDisk"}`, string(serialized))
+}
@@ -0,0 +1,138 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// vRA error codes
+const (
+	InvalidArgumentCode     = 10101
+	RequestNotFoundCode     = 20111
+	CatalogItemNotFoundCode = 20116
+	SystemExceptionCode     = 50505
+)
+
+// ErrTokenExpired is returned when the bearer token given to UseToken has expired or has been
+// revoked, since the client has no credentials to renew it
+var ErrTokenExpired = errors.New("The bearer token has expired or is not valid anymore, a new token has to be issued")
+
+// notFoundCodes are the vRA error codes returned, with various http status
+// codes, when the requested object does not exist
+var notFoundCodes = []int{RequestNotFoundCode, CatalogItemNotFoundCode}
+
+// APIError represents an error from the vRA API.
+type APIError struct {
+	// Method and URL of the request which failed
+	Method string `json:"-"`
+	URL    string `json:"-"`
+	// StatusCode is the http status code of the response
+	StatusCode int     `json:"-"`
+	Errors     []Error `json:"errors"`
+}
+
+//Error API Error
+type Error struct {
+	Code          int         `json:"code"`
+	Source        interface{} `json:"source"`
+	Message       string      `json:"message"`
+	SystemMessage string      `json:"systemMessage"`
+	MoreInfoURL   string      `json:"moreInfoUrl"`
+}
+
+// Error Implement Go error interface for ApiError
+func (e APIError) Error() string {
+	var messages []string
+	for _, err := range e.Errors {
+		message := fmt.Sprintf("%d: %s", err.Code, err.Message)
+		if err.SystemMessage != "" && err.SystemMessage != err.Message {
+			message += fmt.Sprintf(" (%s)", err.SystemMessage)
+		}
+		if err.MoreInfoURL != "" {
+			message += fmt.Sprintf(", see %s", err.MoreInfoURL)
+		}
+		messages = append(messages, message)
+	}
+	if e.Method == "" {
+		return fmt.Sprintf("vRealize API: status %d: %s", e.StatusCode, strings.Join(messages, "; "))
+	}
+	return fmt.Sprintf("vRealize API: %s %s returned status %d: %s", e.Method, e.URL, e.StatusCode, strings.Join(messages, "; "))
+}
+
+// HasCode returns true if the vRA error code is one of the errors
+func (e APIError) HasCode(code int) bool {
+	for _, err := range e.Errors {
+		if err.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAPIError reads an error out of the HTTP response, or does nothing if
+// no error occured. The error is an APIError, NewAPIError returns it with its type.
+func GetAPIError(respBody []byte, statusCode int) error {
+	return *NewAPIError(respBody, statusCode)
+}
+
+// NewAPIError reads the vRA error out of the body of a response with the given status
+// code. A body which is not a vRA error is kept as the message of the error.
+func NewAPIError(respBody []byte, statusCode int) *APIError {
+	apiError := &APIError{StatusCode: statusCode}
+	unmarshalErr := json.Unmarshal(respBody, apiError)
+	if unmarshalErr != nil {
+		// Do not return this error just log it.
+		log.Error("Error is %v ", unmarshalErr)
+	}
+	if len(apiError.Errors) == 0 {
+		apiError.Errors = append(apiError.Errors, Error{
+			Code:    statusCode,
+			Message: string(respBody),
+		})
+	}
+	return apiError
+}
+
+// AsAPIError returns the APIError err holds, if it is one
+func AsAPIError(err error) (*APIError, bool) {
+	switch apiErr := err.(type) {
+	case *APIError:
+		return apiErr, apiErr != nil
+	case APIError:
+		return &apiErr, true
+	}
+	return nil, false
+}
+
+// IsNotFound returns true if the error means the requested object does not exist
+func IsNotFound(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	for _, code := range notFoundCodes {
+		if apiErr.HasCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnauthorized returns true if the request was refused because the client is
+// not authenticated or not allowed to do it
+func IsUnauthorized(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// IsConflict returns true if the request conflicts with the current state of the object
+func IsConflict(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusConflict
+}
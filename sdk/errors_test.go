@@ -0,0 +1,68 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+func TestAPIError(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	catalogItemID := "feaedf73-560c-4612-a573-0041667e0176"
+	url := c.BuildEncodedURL(fmt.Sprintf(RequestTemplateAPI, catalogItemID), nil)
+	server.register(GET, url, respond(400, requestTemplateErrorResponse))
+
+	_, err := c.GetCatalogItemRequestTemplate(catalogItemID)
+	utils.AssertNotNilError(t, err)
+	apiErr, ok := AsAPIError(err)
+	utils.AssertTrue(t, "the error is an APIError", ok)
+	utils.AssertEqualsString(t, GET, apiErr.Method)
+	utils.AssertEqualsString(t, url, apiErr.URL)
+	utils.AssertEqualsInt(t, 400, apiErr.StatusCode)
+	utils.AssertEqualsInt(t, 1, len(apiErr.Errors))
+	utils.AssertEqualsInt(t, CatalogItemNotFoundCode, apiErr.Errors[0].Code)
+	utils.AssertContainsString(t, "Unable to find the specified catalog item", err.Error())
+	utils.AssertContainsString(t, url, err.Error())
+	utils.AssertTrue(t, "an unknown catalog item is not found", IsNotFound(err))
+	utils.AssertFalse(t, "an unknown catalog item is not a conflict", IsConflict(err))
+	utils.AssertFalse(t, "an unknown catalog item is not retryable", c.RetryPolicy.IsRetryable(err))
+}
+
+func TestGetAPIError(t *testing.T) {
+	err := NewAPIError([]byte(invalidResourceErrorResponse), 400)
+	utils.AssertEqualsInt(t, InvalidArgumentCode, err.Errors[0].Code)
+	utils.AssertEqualsString(t, "The provided resource was null.", err.Errors[0].SystemMessage)
+	utils.AssertFalse(t, "an invalid argument is not a not found", IsNotFound(err))
+
+	// GetAPIError returns the same error as an error
+	apiErr, ok := GetAPIError([]byte(invalidResourceErrorResponse), 400).(APIError)
+	utils.AssertTrue(t, "the error is an APIError", ok)
+	utils.AssertEqualsInt(t, InvalidArgumentCode, apiErr.Errors[0].Code)
+
+	// responses which are not vRA errors keep their body as message
+	policy := DefaultRetryPolicy()
+	err = NewAPIError([]byte("<html>Bad Gateway</html>"), 502)
+	utils.AssertEqualsInt(t, 502, err.Errors[0].Code)
+	utils.AssertEqualsString(t, "<html>Bad Gateway</html>", err.Errors[0].Message)
+	utils.AssertTrue(t, "a bad gateway is retryable", policy.IsRetryable(err))
+
+	utils.AssertTrue(t, "404 is not found", IsNotFound(NewAPIError(nil, 404)))
+	utils.AssertTrue(t, "401 is unauthorized", IsUnauthorized(NewAPIError([]byte(unauthorizedResponse), 401)))
+	utils.AssertTrue(t, "403 is unauthorized", IsUnauthorized(NewAPIError(nil, 403)))
+	utils.AssertTrue(t, "409 is a conflict", IsConflict(NewAPIError(nil, 409)))
+	utils.AssertTrue(t, "429 is retryable", policy.IsRetryable(NewAPIError(nil, 429)))
+
+	// the status codes which are retried are the ones of the policy
+	policy.RetryableStatusCodes = []int{503}
+	utils.AssertFalse(t, "a bad gateway is not retryable", policy.IsRetryable(err))
+	utils.AssertTrue(t, "503 is retryable", policy.IsRetryable(NewAPIError(nil, 503)))
+
+	utils.AssertFalse(t, "nil is not found", IsNotFound(nil))
+	utils.AssertFalse(t, "nil is not retryable", policy.IsRetryable(nil))
+	utils.AssertFalse(t, "other errors are not unauthorized", IsUnauthorized(errors.New("unauthorized")))
+}
@@ -0,0 +1,25 @@
+package sdk
+
+import (
+	"net/http"
+)
+
+// NetHTTPClient is the HTTPClient of a new APIClient, it sends the requests with
+// a net/http client
+type NetHTTPClient struct {
+	Client *http.Client
+}
+
+// DoRequest sends the request and returns the response, whatever its status code is
+func (c *NetHTTPClient) DoRequest(req *APIRequest) (*APIResponse, error) {
+	r, err := FromAPIRequestToHTTPRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return readHTTPResponse(resp)
+}
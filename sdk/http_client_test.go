@@ -0,0 +1,180 @@
+package sdk
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+// stubResponse returns an HTTPClient which answers every request with the given status and body
+// and records the requests it receives
+func stubResponse(requests *[]*APIRequest, statusCode int, body string) HTTPClient {
+	return HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+		*requests = append(*requests, req)
+		return &APIResponse{
+			Status:     fmt.Sprintf("%d", statusCode),
+			StatusCode: statusCode,
+			Body:       []byte(body),
+		}, nil
+	})
+}
+
+// fakeResponder answers a request sent to a fakeServer
+type fakeResponder func(req *APIRequest) (*APIResponse, error)
+
+// fakeServer is an HTTPClient which answers the requests with the responder registered for
+// their method and url, and counts the requests it receives by method and url
+type fakeServer struct {
+	lock       sync.Mutex
+	responders map[string]fakeResponder
+	calls      map[string]int
+}
+
+// newFakeServer returns a fakeServer which the client sends its requests to
+func newFakeServer(c *APIClient) *fakeServer {
+	server := &fakeServer{}
+	server.reset()
+	c.HTTPClient = server
+	return server
+}
+
+// register answers the requests with this method and url with the responder
+func (s *fakeServer) register(method, url string, responder fakeResponder) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.responders[method+" "+url] = responder
+}
+
+// reset forgets the responders and the requests received
+func (s *fakeServer) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.responders = make(map[string]fakeResponder)
+	s.calls = make(map[string]int)
+}
+
+// callCount returns the number of requests received with this method and url
+func (s *fakeServer) callCount(method, url string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls[method+" "+url]
+}
+
+// DoRequest answers the request with its responder, registered for the url of the request or,
+// like httpmock, for the url without its query. The responder runs without the lock of the
+// server so that it can block concurrent requests.
+func (s *fakeServer) DoRequest(req *APIRequest) (*APIResponse, error) {
+	key := req.Method + " " + req.URL
+	s.lock.Lock()
+	responder, ok := s.responders[key]
+	if !ok {
+		key = req.Method + " " + strings.SplitN(req.URL, "?", 2)[0]
+		responder, ok = s.responders[key]
+	}
+	s.calls[key]++
+	s.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no responder for %s", key)
+	}
+	return responder(req)
+}
+
+// respond returns a responder which answers with the status and body
+func respond(statusCode int, body string) fakeResponder {
+	return func(req *APIRequest) (*APIResponse, error) {
+		return &APIResponse{Status: fmt.Sprintf("%d", statusCode), StatusCode: statusCode, Body: []byte(body)}, nil
+	}
+}
+
+// failWith returns a responder which fails with the error, like a broken connection
+func failWith(err error) fakeResponder {
+	return func(req *APIRequest) (*APIResponse, error) {
+		return nil, err
+	}
+}
+
+func TestHTTPClientIsInjectable(t *testing.T) {
+	c := newMockClient()
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	var requests []*APIRequest
+	c.HTTPClient = HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+		requests = append(requests, req)
+		body := requestStatusResponse
+		if req.Method == POST {
+			body = validAuthResponse
+		}
+		return &APIResponse{Status: "200 OK", StatusCode: 200, Body: []byte(body)}, nil
+	})
+
+	requestStatus, err := c.GetRequestStatus(mockRequestID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "IN_PROGRESS", requestStatus.Phase)
+
+	// the client logs in first, then sends the request with its token
+	utils.AssertEqualsInt(t, 2, len(requests))
+	utils.AssertEqualsString(t, POST, requests[0].Method)
+	utils.AssertEqualsString(t, Tokens, requests[0].URL)
+	utils.AssertEqualsString(t, "", requests[0].Headers[AuthorizationHeader])
+	utils.AssertEqualsString(t, GET, requests[1].Method)
+	utils.AssertEqualsString(t, url, requests[1].URL)
+	utils.AssertEqualsString(t, c.BearerToken, requests[1].Headers[AuthorizationHeader])
+}
+
+func TestHTTPClientErrorStatus(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	// the responses with an error status are turned into API errors by the client
+	var requests []*APIRequest
+	c.HTTPClient = stubResponse(&requests, 404, requestStatusErrResponse)
+	requestStatus, err := c.GetRequestStatus(mockRequestID)
+	utils.AssertNil(t, requestStatus)
+	utils.AssertTrue(t, "the request is not found", IsNotFound(err))
+	apiErr, ok := AsAPIError(err)
+	utils.AssertTrue(t, "the error is an APIError", ok)
+	utils.AssertEqualsString(t, GET, apiErr.Method)
+	utils.AssertEqualsString(t, url, apiErr.URL)
+	utils.AssertEqualsInt(t, 1, len(requests))
+
+	// and the retry policy applies to them
+	requests = nil
+	c.RetryPolicy.MinBackoff = time.Millisecond
+	c.HTTPClient = stubResponse(&requests, 503, "Service Unavailable")
+	_, err = c.GetRequestStatus(mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, c.RetryPolicy.MaxRetries+1, len(requests))
+}
+
+func TestHTTPClientMiddleware(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MinBackoff = time.Millisecond
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+
+	var requests []*APIRequest
+	next := stubResponse(&requests, 200, requestStatusResponse)
+	calls := 0
+	c.HTTPClient = HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+		calls++
+		if calls == 1 {
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		}
+		return next.DoRequest(req)
+	})
+
+	// the middleware sees every attempt, the retries included
+	_, err := c.GetRequestStatus(mockRequestID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 2, calls)
+	utils.AssertEqualsInt(t, 1, len(requests))
+}
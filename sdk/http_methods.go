@@ -1,66 +1,92 @@
 package sdk
 
 import (
+	"context"
 	"io"
 )
 
 // Get HTTP GET method
 func (c *APIClient) Get(encodedURL string, headers map[string]string) (*APIResponse, error) {
+	return c.GetWithContext(context.Background(), encodedURL, headers)
+}
+
+// GetWithContext HTTP GET method, aborted when ctx is cancelled
+func (c *APIClient) GetWithContext(ctx context.Context, encodedURL string, headers map[string]string) (*APIResponse, error) {
 	req := &APIRequest{
 		Method:  GET,
 		URL:     encodedURL,
 		Headers: headers,
 	}
-	return c.doRequest(req)
+	return c.doRequest(req.WithContext(ctx))
 }
 
 // Post HTTP POST method
 func (c *APIClient) Post(url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
+	return c.PostWithContext(context.Background(), url, body, headers)
+}
+
+// PostWithContext HTTP POST method, aborted when ctx is cancelled
+func (c *APIClient) PostWithContext(ctx context.Context, url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
 	req := &APIRequest{
 		Method:  POST,
 		URL:     url,
 		Body:    body,
 		Headers: headers,
 	}
-	return c.doRequest(req)
+	return c.doRequest(req.WithContext(ctx))
 }
 
 // Put HTTP PUT method
 func (c *APIClient) Put(url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
+	return c.PutWithContext(context.Background(), url, body, headers)
+}
+
+// PutWithContext HTTP PUT method, aborted when ctx is cancelled
+func (c *APIClient) PutWithContext(ctx context.Context, url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
 	req := &APIRequest{
 		Method:  PUT,
 		URL:     url,
 		Body:    body,
 		Headers: headers,
 	}
-	return c.doRequest(req)
+	return c.doRequest(req.WithContext(ctx))
 }
 
 // Patch HTTP PATCH method
 func (c *APIClient) Patch(url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
+	return c.PatchWithContext(context.Background(), url, body, headers)
+}
+
+// PatchWithContext HTTP PATCH method, aborted when ctx is cancelled
+func (c *APIClient) PatchWithContext(ctx context.Context, url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
 	req := &APIRequest{
 		Method:  PATCH,
 		URL:     url,
 		Body:    body,
 		Headers: headers,
 	}
-	return c.doRequest(req)
+	return c.doRequest(req.WithContext(ctx))
 }
 
 // Delete HTTP DELETE method
 func (c *APIClient) Delete(url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
+	return c.DeleteWithContext(context.Background(), url, body, headers)
+}
+
+// DeleteWithContext HTTP DELETE method, aborted when ctx is cancelled
+func (c *APIClient) DeleteWithContext(ctx context.Context, url string, body io.Reader, headers map[string]string) (*APIResponse, error) {
 	req := &APIRequest{
 		Method:  DELETE,
 		URL:     url,
 		Body:    body,
 		Headers: headers,
 	}
-	return c.doRequest(req)
+	return c.doRequest(req.WithContext(ctx))
 }
 
 // doRequest makes the request and returns the response
 func (c *APIClient) doRequest(req *APIRequest) (*APIResponse, error) {
-	apiResp, err := c.DoRequest(req, false)
+	apiResp, err := c.DoRequest(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,28 @@
 package sdk
 
-// HTTPClient interface
+// HTTPClient sends one request to the vRA server and returns its response, whatever
+// its status code is. An error is only returned if no response was received.
+//
+// APIClient sends all its requests through its HTTPClient, after adding the bearer
+// token, and handles the retries, the authentication and the API errors itself. Wrap
+// the HTTPClient of an APIClient to add middleware, like metrics or auditing, or
+// replace it to use another transport.
 type HTTPClient interface {
 	DoRequest(req *APIRequest) (*APIResponse, error)
 }
+
+// HTTPClientFunc is an adapter to use an ordinary function as an HTTPClient
+//
+//	next := c.HTTPClient
+//	c.HTTPClient = HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+//		start := time.Now()
+//		resp, err := next.DoRequest(req)
+//		log.Printf("%s %s took %v", req.Method, req.URL, time.Since(start))
+//		return resp, err
+//	})
+type HTTPClientFunc func(req *APIRequest) (*APIResponse, error)
+
+// DoRequest calls f(req)
+func (f HTTPClientFunc) DoRequest(req *APIRequest) (*APIResponse, error) {
+	return f(req)
+}
@@ -0,0 +1,84 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+)
+
+// lookupCache memoizes the results of the lookups of objects which do not change during a
+// terraform run, like the catalog items, their request templates and the business groups.
+// A lookup which is already in flight is shared by all its callers. Errors are not cached,
+// the next caller looks the key up again.
+type lookupCache struct {
+	lock    sync.Mutex
+	entries map[string]*lookupEntry
+}
+
+// lookupEntry is the result of a lookup, which is ready once done is closed. A lookup
+// which has failed because the context of its caller was cancelled is abandoned.
+type lookupEntry struct {
+	done      chan struct{}
+	value     interface{}
+	err       error
+	abandoned bool
+}
+
+// do returns the cached result for the key, or calls lookup to get it. Callers waiting for
+// a lookup in flight return early with the error of ctx if it is cancelled. If the caller
+// running the lookup is cancelled instead, the waiters whose ctx is still live look the key
+// up again rather than failing with its error, and so do all the waiters if the lookup panics.
+func (c *lookupCache) do(ctx context.Context, key string, lookup func() (interface{}, error)) (interface{}, error) {
+	for {
+		c.lock.Lock()
+		if c.entries == nil {
+			c.entries = make(map[string]*lookupEntry)
+		}
+		entry, ok := c.entries[key]
+		if !ok {
+			break
+		}
+		c.lock.Unlock()
+		select {
+		case <-entry.done:
+			if entry.abandoned && ctx.Err() == nil {
+				continue
+			}
+			return entry.value, entry.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	entry := &lookupEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.lock.Unlock()
+
+	// the entry is settled even if lookup panics, a lookup which has panicked is abandoned
+	returned := false
+	defer func() {
+		if !returned || entry.err != nil {
+			entry.abandoned = !returned || ctx.Err() != nil
+			c.lock.Lock()
+			if c.entries[key] == entry {
+				delete(c.entries, key)
+			}
+			c.lock.Unlock()
+		}
+		close(entry.done)
+	}()
+	entry.value, entry.err = lookup()
+	returned = true
+	return entry.value, entry.err
+}
+
+// ClearLookupCache forgets the catalog items, request templates and business groups the
+// client has looked up, so that they are read from the server again
+func (c *APIClient) ClearLookupCache() {
+	c.lookups.clear()
+}
+
+// clear forgets all the cached results
+func (c *lookupCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+}
@@ -0,0 +1,190 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+func TestLookupCache(t *testing.T) {
+	var cache lookupCache
+	calls := 0
+	release := make(chan struct{})
+	lookup := func() (interface{}, error) {
+		calls++
+		<-release
+		return "feaedf73-560c-4612-a573-41667e017691", nil
+	}
+
+	// the concurrent lookups of the same key share one call
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.do(context.Background(), "catalogItemID/CentOs", lookup)
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+	utils.AssertEqualsInt(t, 1, calls)
+	for _, result := range results {
+		utils.AssertEqualsString(t, "feaedf73-560c-4612-a573-41667e017691", result.(string))
+	}
+
+	// and so do the following ones
+	value, err := cache.do(context.Background(), "catalogItemID/CentOs", lookup)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "feaedf73-560c-4612-a573-41667e017691", value.(string))
+	utils.AssertEqualsInt(t, 1, calls)
+
+	// errors are not cached
+	failures := 0
+	failing := func() (interface{}, error) {
+		failures++
+		return nil, fmt.Errorf("Catalog item, Invalid not found")
+	}
+	for i := 0; i < 2; i++ {
+		_, err = cache.do(context.Background(), "catalogItemID/Invalid", failing)
+		utils.AssertNotNilError(t, err)
+	}
+	utils.AssertEqualsInt(t, 2, failures)
+
+	cache.clear()
+	_, err = cache.do(context.Background(), "catalogItemID/CentOs", lookup)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 2, calls)
+}
+
+func TestLookupCacheCancelledWait(t *testing.T) {
+	var cache lookupCache
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go cache.do(context.Background(), "businessGroupID/qe/Development", func() (interface{}, error) {
+		close(started)
+		<-release
+		return "b2470b94-cbca-43db-be37-803cca7b0f1a", nil
+	})
+	<-started
+
+	// a caller waiting for the lookup in flight gives up when its context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.do(ctx, "businessGroupID/qe/Development", func() (interface{}, error) {
+		t.Fatal("the lookup in flight is not shared")
+		return nil, nil
+	})
+	utils.AssertEqualsString(t, context.Canceled.Error(), err.Error())
+}
+
+func TestLookupCacheCancelledLookup(t *testing.T) {
+	var cache lookupCache
+	key := "businessGroupID/qe/Development"
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	lookupErr := make(chan error, 1)
+	go func() {
+		_, err := cache.do(ctx, key, func() (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		lookupErr <- err
+	}()
+	<-started
+
+	// a caller waiting for the lookup in flight is not failed by the cancellation of the
+	// caller running it, it looks the key up again
+	var value interface{}
+	var err error
+	waited := make(chan struct{})
+	go func() {
+		value, err = cache.do(context.Background(), key, func() (interface{}, error) {
+			return "b2470b94-cbca-43db-be37-803cca7b0f1a", nil
+		})
+		close(waited)
+	}()
+	// gives the waiter the time to join the lookup in flight
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	utils.AssertEqualsString(t, context.Canceled.Error(), (<-lookupErr).Error())
+	<-waited
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "b2470b94-cbca-43db-be37-803cca7b0f1a", value.(string))
+}
+
+func TestLookupCachePanickedLookup(t *testing.T) {
+	var cache lookupCache
+	key := "businessGroupID/qe/Development"
+	started := make(chan struct{})
+	release := make(chan struct{})
+	recovered := make(chan interface{}, 1)
+	go func() {
+		defer func() { recovered <- recover() }()
+		cache.do(context.Background(), key, func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("lookup failed")
+		})
+	}()
+	<-started
+
+	// the panic is raised to the caller running the lookup, and the callers waiting for it
+	// look the key up again instead of waiting forever
+	var value interface{}
+	var err error
+	waited := make(chan struct{})
+	go func() {
+		value, err = cache.do(context.Background(), key, func() (interface{}, error) {
+			return "b2470b94-cbca-43db-be37-803cca7b0f1a", nil
+		})
+		close(waited)
+	}()
+	// gives the waiter the time to join the lookup in flight
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	utils.AssertEqualsString(t, "lookup failed", fmt.Sprint(<-recovered))
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiter is still waiting for the lookup which panicked")
+	}
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "b2470b94-cbca-43db-be37-803cca7b0f1a", value.(string))
+
+	// and the value the waiter has looked up is cached
+	value, err = cache.do(context.Background(), key, func() (interface{}, error) {
+		return nil, fmt.Errorf("not looked up again")
+	})
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "b2470b94-cbca-43db-be37-803cca7b0f1a", value.(string))
+}
+
+func TestRequestTemplateIsCached(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	catalogItemID := "feaedf73-560c-4612-a573-41667e017691"
+	url := c.BuildEncodedURL(fmt.Sprintf(RequestTemplateAPI, catalogItemID), nil)
+	server.register(GET, url, respond(200, requestTemplateResponse))
+
+	requestTemplate, err := c.GetCatalogItemRequestTemplate(catalogItemID)
+	utils.AssertNilError(t, err)
+	requestTemplate.Description = "changed by the caller"
+	requestTemplate.Data["mock.changed.by.caller"] = 30
+
+	// every caller gets its own copy of the template
+	requestTemplate, err = c.GetCatalogItemRequestTemplate(catalogItemID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "", requestTemplate.Description)
+	_, ok := requestTemplate.Data["mock.changed.by.caller"]
+	utils.AssertFalse(t, "the changes of the caller are not cached", ok)
+	utils.AssertEqualsInt(t, 1, server.callCount(GET, url))
+}
@@ -2249,4 +2249,143 @@ var (
 		   }
 		]
 	 }`
+
+	validAuthResponse = `{
+		"expires":"2099-02-26T03:32:35.000Z",
+		"id":"MTU1MTEyMzE1NTc5ODpiYTZkYjdhNjZlNGNkYjZmZTBiMjp0ZW5hbnQ6cWV1c2VybmFtZTpmcml0ekBjb2tlLnNxYS1ob3Jpem9uLmxvY2Fs",
+		"tenant":"qe"
+	 }`
+
+	expiredAuthResponse = `{
+		"expires":"2019-02-26T03:32:35.000Z",
+		"id":"MTU1MTEyMzE1NTc5ODpiYTZkYjdhNjZlNGNkYjZmZTBiMjp0ZW5hbnQ6cWV1c2VybmFtZTpmcml0ekBjb2tlLnNxYS1ob3Jpem9uLmxvY2Fs",
+		"tenant":"qe"
+	 }`
+
+	unauthorizedResponse = `{
+		"errors":[
+		   {
+			  "code":401,
+			  "source":null,
+			  "message":"Unauthorized",
+			  "systemMessage":null,
+			  "moreInfoUrl":null
+		   }
+		]
+	 }`
+
+	duplicateEntitledCatalogItemViewsResponse = `{
+		"links":[],
+		"content":[
+		   {
+			  "@type":"ConsumerEntitledCatalogItemView",
+			  "catalogItemId":"feaedf73-560c-4612-a573-41667e017691",
+			  "name":"CentOs"
+		   },
+		   {
+			  "@type":"ConsumerEntitledCatalogItemView",
+			  "catalogItemId":"0a5d0d1e-3d9b-4bb2-bb3e-a1dce56e4af9",
+			  "name":"CentOs"
+		   }
+		],
+		"metadata":{
+		   "size":20,
+		   "totalElements":2,
+		   "totalPages":1,
+		   "number":1,
+		   "offset":0
+		}
+	 }`
+
+	nullNameEntitledCatalogItemViewsResponse = `{
+		"links":[],
+		"content":[
+		   {
+			  "@type":"ConsumerEntitledCatalogItemView",
+			  "catalogItemId":"2e13fd45-a85e-4985-b89e-4ebb19ab272c",
+			  "name":null,
+			  "description":null,
+			  "entitledOrganizations":null,
+			  "catalogItemTypeRef":null
+		   },
+		   {
+			  "@type":"ConsumerEntitledCatalogItemView",
+			  "catalogItemId":"feaedf73-560c-4612-a573-41667e017691",
+			  "name":"CentOs"
+		   }
+		],
+		"metadata":{
+		   "size":20,
+		   "totalElements":2,
+		   "totalPages":1,
+		   "number":1,
+		   "offset":0
+		}
+	 }`
+
+	entitledCatalogItemViewResponse = `{
+		"@type":"ConsumerEntitledCatalogItemView",
+		"entitledOrganizations":[
+		   {
+			  "tenantRef":"qe",
+			  "tenantLabel":"QETenant",
+			  "subtenantRef":"b2470b94-cbca-43db-be37-803cca7b0f1a",
+			  "subtenantLabel":"Development"
+		   }
+		],
+		"catalogItemId":"feaedf73-560c-4612-a573-41667e017691",
+		"name":"CentOs",
+		"description":"",
+		"isNoteworthy":false,
+		"dateCreated":"2018-09-18T20:04:55.805Z",
+		"lastUpdatedDate":"2018-09-27T22:52:48.390Z",
+		"links":[],
+		"iconId":"composition.blueprint.png",
+		"catalogItemTypeRef":{
+		   "id":"com.vmware.csp.component.cafe.composition.blueprint",
+		   "label":"Composite Blueprint"
+		},
+		"serviceRef":{
+		   "id":"d33afbc2-954d-416d-8d4a-bc4ad1b66058",
+		   "label":"test-service"
+		},
+		"outputResourceTypeRef":{
+		   "id":"composition.resource.type.deployment",
+		   "label":"Deployment"
+		}
+	 }`
+
+	requestNotCancellableResponse = `{
+		"errors":[
+		   {
+			  "code":20140,
+			  "source":null,
+			  "message":"The request cannot be cancelled in its current state.",
+			  "systemMessage":"The request cannot be cancelled in its current state.",
+			  "moreInfoUrl":null
+		   }
+		]
+	 }`
+
+	aboutResponse = `{
+		"releaseVersion":"7.5.0 (build 10053500)",
+		"buildNumber":"10053500",
+		"buildDate":"2018-09-11T23:23:01.000Z",
+		"productBuildNumber":"10053500"
+	 }`
+
+	entitledResourceResponse = `{
+		"@type":"CatalogResource",
+		"id":"69ddca78-7ed7-45e2-9f05-a4a4c8803e5c",
+		"iconId":"composition.blueprint.png",
+		"resourceTypeRef":{
+		   "id":"composition.resource.type.deployment",
+		   "label":"Deployment"
+		},
+		"name":"Prativa_CentOs-66559687",
+		"description":"Prativa's deployment 1",
+		"status":"ACTIVE",
+		"requestId":"6ec160e5-41c5-4b1d-8ddc-e89c426957c6",
+		"requestState":"SUCCESSFUL"
+	 }`
 )
@@ -0,0 +1,125 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+// pagination constants
+const (
+	// DefaultPageSize is the number of elements fetched per page by the list calls of the sdk
+	DefaultPageSize = 20
+
+	PageQueryParam   = "page"
+	LimitQueryParam  = "limit"
+	FilterQueryParam = "$filter"
+)
+
+// ODataEquals returns an OData filter expression, to be sent as the FilterQueryParam of a
+// list call, that only matches the elements whose field is equal to value
+func ODataEquals(field, value string) string {
+	// single quotes are escaped by doubling them in OData string literals
+	return fmt.Sprintf("%s eq '%s'", field, strings.Replace(value, "'", "''", -1))
+}
+
+// Page is one page of a paginated vRA list response. Content holds the raw json
+// of the elements of the page, to be unmarshalled by the caller.
+type Page struct {
+	Links    json.RawMessage `json:"links,omitempty"`
+	Content  json.RawMessage `json:"content,omitempty"`
+	Metadata Metadata        `json:"metadata"`
+}
+
+// UnmarshalContent decodes the elements of the page into v
+func (p *Page) UnmarshalContent(v interface{}) error {
+	if len(p.Content) == 0 {
+		return nil
+	}
+	return utils.UnmarshalJSON(p.Content, v)
+}
+
+// Pager walks through the pages of a vRA list endpoint, one page at a time.
+// Stop calling Next to stop fetching the pages that are left.
+//
+//	pager := c.NewPager(path, nil, DefaultPageSize)
+//	for pager.HasNext() {
+//		page, err := pager.Next(ctx)
+//		...
+//	}
+type Pager struct {
+	client          *APIClient
+	path            string
+	queryParameters map[string]string
+	pageSize        int
+	page            int
+	totalPages      int
+}
+
+// NewPager returns a Pager over the list endpoint at the relative path. The query parameters
+// are sent with every page and a pageSize of 0 or less means DefaultPageSize.
+func (c *APIClient) NewPager(path string, queryParameters map[string]string, pageSize int) *Pager {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Pager{
+		client:          c,
+		path:            path,
+		queryParameters: queryParameters,
+		pageSize:        pageSize,
+		totalPages:      1,
+	}
+}
+
+// HasNext returns true if there are pages left to fetch
+func (p *Pager) HasNext() bool {
+	return p.page < p.totalPages
+}
+
+// Next fetches the next page
+func (p *Pager) Next(ctx context.Context) (*Page, error) {
+	queryParameters := map[string]string{
+		PageQueryParam:  strconv.Itoa(p.page + 1),
+		LimitQueryParam: strconv.Itoa(p.pageSize),
+	}
+	for key, value := range p.queryParameters {
+		queryParameters[key] = value
+	}
+	url := p.client.BuildEncodedURL(p.path, queryParameters)
+	resp, respErr := p.client.GetWithContext(ctx, url, nil)
+	if respErr != nil {
+		return nil, respErr
+	}
+
+	var page Page
+	unmarshallErr := utils.UnmarshalJSON(resp.Body, &page)
+	if unmarshallErr != nil {
+		return nil, unmarshallErr
+	}
+	p.page++
+	// endpoints which are not paginated return no metadata, they only have one page
+	p.totalPages = page.Metadata.TotalPages
+	return &page, nil
+}
+
+// ForEachPage fetches the pages of the list endpoint at the relative path and calls fn with
+// each of them, until there are no more pages, fn returns false or an error occurs.
+func (c *APIClient) ForEachPage(ctx context.Context, path string, queryParameters map[string]string,
+	pageSize int, fn func(page *Page) (bool, error)) error {
+	pager := c.NewPager(path, queryParameters, pageSize)
+	for pager.HasNext() {
+		page, err := pager.Next(ctx)
+		if err != nil {
+			return err
+		}
+		more, err := fn(page)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+// businessGroupsPageResponder returns pages of pageSize business groups named
+// "Group <n>", out of totalElements groups. A "name eq" filter is applied.
+func businessGroupsPageResponder(totalElements int) fakeResponder {
+	return func(req *APIRequest) (*APIResponse, error) {
+		reqURL, err := url.Parse(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		page, _ := strconv.Atoi(reqURL.Query().Get("page"))
+		pageSize, _ := strconv.Atoi(reqURL.Query().Get("limit"))
+		filter := reqURL.Query().Get("$filter")
+
+		var names []string
+		for i := 0; i < totalElements; i++ {
+			name := fmt.Sprintf("Group %d", i)
+			if filter == "" || filter == ODataEquals("name", name) {
+				names = append(names, name)
+			}
+		}
+		totalElements := len(names)
+		totalPages := (totalElements + pageSize - 1) / pageSize
+
+		content := ""
+		for i := (page - 1) * pageSize; i < page*pageSize && i < totalElements; i++ {
+			if content != "" {
+				content += ","
+			}
+			content += fmt.Sprintf(`{"name":"%s","id":"group-%s"}`, names[i], strings.TrimPrefix(names[i], "Group "))
+		}
+		body := fmt.Sprintf(`{"links":[],"content":[%s],"metadata":{"size":%d,"totalElements":%d,"totalPages":%d,"number":%d}}`,
+			content, pageSize, totalElements, totalPages, page)
+		return respond(200, body)(req)
+	}
+}
+
+func TestPager(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	path := Tenants + "/" + mockTenant + "/subtenants"
+	url := c.BuildEncodedURL(path, nil)
+	server.register(GET, url, businessGroupsPageResponder(65))
+
+	pager := c.NewPager(path, nil, 10)
+	var names []string
+	for pager.HasNext() {
+		page, err := pager.Next(context.Background())
+		utils.AssertNilError(t, err)
+		var businessGroups []BusinessGroup
+		utils.AssertNilError(t, page.UnmarshalContent(&businessGroups))
+		for _, businessGroup := range businessGroups {
+			names = append(names, businessGroup.Name)
+		}
+	}
+	utils.AssertEqualsInt(t, 65, len(names))
+	utils.AssertEqualsString(t, "Group 64", names[64])
+	utils.AssertEqualsInt(t, 7, server.callCount(GET, url))
+
+	// stop after the page containing the element
+	server.reset()
+	server.register(GET, url, businessGroupsPageResponder(65))
+	pages := 0
+	err := c.ForEachPage(context.Background(), path, nil, 0, func(page *Page) (bool, error) {
+		pages++
+		return page.Metadata.Number < 2, nil
+	})
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 2, pages)
+	utils.AssertEqualsInt(t, 2, server.callCount(GET, url))
+}
+
+func TestGetBusinessGroupIDWithManyGroups(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	path := Tenants + "/" + mockTenant + "/subtenants"
+	url := c.BuildEncodedURL(path, nil)
+	server.register(GET, url, businessGroupsPageResponder(65))
+
+	id, err := c.GetBusinessGroupID("Group 61", mockTenant)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "group-61", id)
+	// the lookup is filtered on the server, it only takes one request
+	utils.AssertEqualsInt(t, 1, server.callCount(GET, url))
+
+	id, err = c.GetBusinessGroupID("Group 65", mockTenant)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsString(t, "", id)
+}
+
+func TestODataEquals(t *testing.T) {
+	utils.AssertEqualsString(t, "name eq 'Development'", ODataEquals("name", "Development"))
+	utils.AssertEqualsString(t, "name eq 'Bob''s machines'", ODataEquals("name", "Bob's machines"))
+}
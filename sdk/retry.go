@@ -0,0 +1,119 @@
+package sdk
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// retry policy defaults
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 1 * time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy controls how the client retries requests that failed because of a
+// transient error, like a connection reset or a gateway error from the appliance.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried, 0 disables retries
+	MaxRetries int
+	// MinBackoff is the wait before the first retry, it doubles on every retry
+	MinBackoff time.Duration
+	// MaxBackoff caps the wait between two retries
+	MaxBackoff time.Duration
+	// RetryableStatusCodes are the response status codes that are worth a retry
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy returns the retry policy used by a new client
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		RetryableStatusCodes: []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// Backoff returns how long to wait before the given retry (starting at 0). The wait grows
+// exponentially up to MaxBackoff and a random jitter of up to half of it is subtracted, so that
+// parallel requests do not hit the appliance again at the same time.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	wait := p.MinBackoff
+	for i := 0; i < retry && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait - time.Duration(rand.Int63n(int64(wait)/2+1))
+}
+
+// IsRetryableStatus returns true if a response with this status code can be retried
+func (p RetryPolicy) IsRetryableStatus(statusCode int) bool {
+	for _, code := range p.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable returns true if the request failed with a transient error and can be sent again:
+// a network error, a response with one of the RetryableStatusCodes, or a 429 Too Many Requests
+func (p RetryPolicy) IsRetryable(err error) bool {
+	if apiErr, ok := AsAPIError(err); ok {
+		return apiErr.StatusCode == http.StatusTooManyRequests || p.IsRetryableStatus(apiErr.StatusCode)
+	}
+	return err != nil && isTransientError(err)
+}
+
+// shouldRetry decides if a request which got the response resp or the error err is sent again.
+// Requests which are not idempotent are only retried if they never reached the server.
+func (p RetryPolicy) shouldRetry(idempotent bool, resp *APIResponse, err error) bool {
+	if err != nil {
+		if idempotent {
+			return isTransientError(err)
+		}
+		return isDialError(err)
+	}
+	return idempotent && p.IsRetryableStatus(resp.StatusCode)
+}
+
+// isIdempotent returns true for the http methods that can safely be replayed
+func isIdempotent(method string) bool {
+	return method == GET || method == PUT || method == DELETE
+}
+
+// isTransientError returns true for network errors, like a reset or refused connection or
+// a connection closed by the server before it responded
+func isTransientError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// isDialError returns true if the connection to the server could not be opened
+func isDialError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
@@ -18,11 +18,14 @@ type ResourceActionTemplate struct {
 // RequestStatusView - used to store REST response of
 // request triggered against any resource.
 type RequestStatusView struct {
-	RequestCompletion struct {
-		RequestCompletionState string `json:"requestCompletionState"`
-		CompletionDetails      string `json:"CompletionDetails"`
-	} `json:"requestCompletion"`
-	Phase string `json:"phase"`
+	RequestCompletion RequestCompletion `json:"requestCompletion"`
+	Phase             string            `json:"phase"`
+}
+
+// RequestCompletion - the outcome of a completed request
+type RequestCompletion struct {
+	RequestCompletionState string `json:"requestCompletionState"`
+	CompletionDetails      string `json:"CompletionDetails"`
 }
 
 // BusinessGroups - list of business groups
@@ -55,9 +58,16 @@ type DeploymentResource struct {
 	RequestID       string                 `json:"requestId,omitempty"`
 	ResourceID      string                 `json:"resourceId,omitempty"`
 	ResourceType    string                 `json:"resourceType,omitempty"`
+	Lease           *Lease                 `json:"lease,omitempty"`
 	ResourcesData   DeploymentResourceData `json:"data,omitempty"`
 }
 
+// Lease - the lease period of a provisioned resource
+type Lease struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
 // DeploymentResourceData - view of the resources/machines in a deployment
 type DeploymentResourceData struct {
 	Memory                      int    `json:"MachineMemory,omitempty"`
@@ -160,7 +170,7 @@ type CatalogRequest struct {
 	DateCompleted            time.Time              `json:"dateCompleted"`
 	Quote                    interface{}            `json:"quote"`
 	RequestData              map[string]interface{} `json:"requestData"`
-	RequestCompletion        string                 `json:"requestCompletion"`
+	RequestCompletion        *RequestCompletion     `json:"requestCompletion"`
 	RetriesRemaining         int                    `json:"retriesRemaining"`
 	RequestedItemName        string                 `json:"requestedItemName"`
 	RequestedItemDescription string                 `json:"requestedItemDescription"`
@@ -210,9 +220,57 @@ type CatalogItem struct {
 // EntitledCatalogItemViews represents catalog items in an active state, the current user
 // is entitled to consume
 type EntitledCatalogItemViews struct {
-	Links    interface{} `json:"links"`
-	Content  interface{} `json:"content"`
-	Metadata Metadata    `json:"metadata"`
+	Links    []Link                    `json:"links"`
+	Content  []EntitledCatalogItemView `json:"content"`
+	Metadata Metadata                  `json:"metadata"`
+}
+
+// EntitledCatalogItemView - summary of a catalog item the current user is entitled to consume
+type EntitledCatalogItemView struct {
+	Type                  string                 `json:"@type,omitempty"`
+	CatalogItemID         string                 `json:"catalogItemId,omitempty"`
+	Name                  string                 `json:"name,omitempty"`
+	Description           string                 `json:"description,omitempty"`
+	IsNoteworthy          bool                   `json:"isNoteworthy,omitempty"`
+	DateCreated           *time.Time             `json:"dateCreated,omitempty"`
+	LastUpdatedDate       *time.Time             `json:"lastUpdatedDate,omitempty"`
+	IconID                string                 `json:"iconId,omitempty"`
+	EntitledOrganizations []EntitledOrganization `json:"entitledOrganizations,omitempty"`
+	CatalogItemTypeRef    Reference              `json:"catalogItemTypeRef,omitempty"`
+	ServiceRef            Reference              `json:"serviceRef,omitempty"`
+	OutputResourceTypeRef Reference              `json:"outputResourceTypeRef,omitempty"`
+	Links                 []Link                 `json:"links,omitempty"`
+}
+
+// IsEntitled returns true if the members of the business group are entitled to the catalog item
+func (v EntitledCatalogItemView) IsEntitled(businessGroupID string) bool {
+	for _, organization := range v.EntitledOrganizations {
+		if organization.SubtenantRef == businessGroupID {
+			return true
+		}
+	}
+	return false
+}
+
+// EntitledOrganization - a tenant and business group entitled to a catalog item
+type EntitledOrganization struct {
+	TenantRef      string `json:"tenantRef,omitempty"`
+	TenantLabel    string `json:"tenantLabel,omitempty"`
+	SubtenantRef   string `json:"subtenantRef,omitempty"`
+	SubtenantLabel string `json:"subtenantLabel,omitempty"`
+}
+
+// Reference - id and label of the vRA object a property refers to
+type Reference struct {
+	ID    string `json:"id,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+// Link - link to a related API call
+type Link struct {
+	Type string `json:"@type,omitempty"`
+	Rel  string `json:"rel,omitempty"`
+	Href string `json:"href,omitempty"`
 }
 
 // Metadata - Metadata  used to store metadata of resource list response
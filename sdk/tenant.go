@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// tenantIDPattern matches the ids of the tenants, which vRA restricts to letters, digits, dots,
+// hyphens and underscores
+var tenantIDPattern = regexp.MustCompile(`^[\w.-]+$`)
+
+// tenantClients are the clients a client has created for the other tenants, by tenant
+type tenantClients struct {
+	lock    sync.Mutex
+	clients map[string]*APIClient
+}
+
+// ForTenant returns a client which sends the requests to the given tenant. It logs in
+// to the tenant with the credentials of this client, which have to be the ones of an
+// account entitled to the tenant, like a system administrator. The clients are created
+// once per tenant and share the connections of this client, each one has its own token.
+// An empty tenant, or the tenant of this client, returns this client.
+func (c *APIClient) ForTenant(tenant string) (*APIClient, error) {
+	tenant = strings.TrimSpace(tenant)
+	if tenant == "" || tenant == c.Tenant {
+		return c, nil
+	}
+	c.tokenLock.Lock()
+	preIssuedToken := c.preIssuedToken
+	c.tokenLock.Unlock()
+	if preIssuedToken {
+		return nil, fmt.Errorf("Unable to log in to the tenant %s, a username and password are required to log in to another tenant than the one of the token", tenant)
+	}
+
+	c.tenants.lock.Lock()
+	defer c.tenants.lock.Unlock()
+	if client, ok := c.tenants.clients[tenant]; ok {
+		return client, nil
+	}
+	client := &APIClient{
+		Username:    c.Username,
+		Password:    c.Password,
+		BaseURL:     c.BaseURL,
+		Tenant:      tenant,
+		Insecure:    c.Insecure,
+		Client:      c.Client,
+		HTTPClient:  c.HTTPClient,
+		RetryPolicy: c.RetryPolicy,
+		Trace:       c.Trace,
+		Version:     c.Version,
+	}
+	if c.tenants.clients == nil {
+		c.tenants.clients = make(map[string]*APIClient)
+	}
+	c.tenants.clients[tenant] = client
+	log.Info("Created a client for the tenant %s", tenant)
+	return client, nil
+}
+
+// IsTenant returns true if the tenant exists on the server. The tenant of this client and the
+// tenants it has created a client for are known, the other ones are read from the server. A
+// tenant the account is not allowed to read is reported as not existing.
+func (c *APIClient) IsTenant(ctx context.Context, tenant string) (bool, error) {
+	if !tenantIDPattern.MatchString(tenant) {
+		return false, nil
+	}
+	if tenant == c.Tenant {
+		return true, nil
+	}
+	c.tenants.lock.Lock()
+	_, ok := c.tenants.clients[tenant]
+	c.tenants.lock.Unlock()
+	if ok {
+		return true, nil
+	}
+
+	url := c.BuildEncodedURL(Tenants+"/"+tenant, nil)
+	_, err := c.GetWithContext(ctx, url, nil)
+	if IsNotFound(err) || IsUnauthorized(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// logout revokes the tokens of the clients of the other tenants concurrently, and returns
+// the first error
+func (t *tenantClients) logout(ctx context.Context) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+	for tenant, client := range t.clients {
+		wg.Add(1)
+		go func(tenant string, client *APIClient) {
+			defer wg.Done()
+			if err := client.Logout(ctx); err != nil {
+				log.Warning("Unable to revoke the token of the tenant %s: %v", tenant, err)
+				errLock.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errLock.Unlock()
+			}
+		}(tenant, client)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,101 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+func TestForTenant(t *testing.T) {
+	c := newMockClient()
+	server := newFakeServer(c)
+
+	tokenURL := fmt.Sprintf("%s"+Tokens, mockBaseURL)
+	var tenants []string
+	server.register(POST, tokenURL, func(req *APIRequest) (*APIResponse, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		var auth AuthenticationRequest
+		json.Unmarshal(body, &auth)
+		tenants = append(tenants, auth.Tenant)
+		return respond(200, validAuthResponse)(req)
+	})
+	path := Tenants + "/finance/subtenants"
+	server.register(GET, c.BuildEncodedURL(path, nil), respond(200, subTenantsResponse))
+
+	// the tenant of the client is the client itself
+	tenantClient, err := c.ForTenant("")
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the client of the provider tenant", tenantClient == c)
+	tenantClient, err = c.ForTenant(mockTenant)
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the client of the provider tenant", tenantClient == c)
+
+	// another tenant has its own client, created once
+	tenantClient, err = c.ForTenant("finance")
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "a client for the tenant", tenantClient != c)
+	utils.AssertEqualsString(t, "finance", tenantClient.Tenant)
+	sameClient, err := c.ForTenant("finance")
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the client is reused", sameClient == tenantClient)
+
+	// which logs in to its tenant
+	id, err := tenantClient.GetBusinessGroupID("Development", tenantClient.Tenant)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "b2470b94-cbca-43db-be37-803cca7b0f1a", id)
+	utils.AssertEqualsInt(t, 1, len(tenants))
+	utils.AssertEqualsString(t, "finance", tenants[0])
+	utils.AssertEqualsString(t, "", c.BearerToken)
+
+	// and is logged out with the client
+	mockToken := "MTU1MTEyMzE1NTc5ODpiYTZkYjdhNjZlNGNkYjZmZTBiMjp0ZW5hbnQ6cWV1c2VybmFtZTpmcml0ekBjb2tlLnNxYS1ob3Jpem9uLmxvY2Fs"
+	server.register(DELETE, tokenURL+"/"+mockToken, respond(204, ""))
+	err = c.Logout(context.Background())
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "", tenantClient.BearerToken)
+	utils.AssertEqualsInt(t, 1, server.callCount(DELETE, tokenURL+"/"+mockToken))
+
+	// a pre-issued token cannot log in to another tenant
+	server.register(HEAD, tokenURL+"/"+mockToken, respond(204, ""))
+	tokenClient := newMockClient()
+	tokenClient.HTTPClient = c.HTTPClient
+	err = tokenClient.UseToken(context.Background(), mockToken)
+	utils.AssertNilError(t, err)
+	_, err = tokenClient.ForTenant("finance")
+	utils.AssertNotNilError(t, err)
+}
+
+func TestIsTenant(t *testing.T) {
+	c := newMockClient()
+	c.Tenant = "vsphere.local"
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+	server.register(GET, c.BuildEncodedURL(Tenants+"/finance", nil), respond(200, `{"id":"finance","urlName":"finance"}`))
+	server.register(GET, c.BuildEncodedURL(Tenants+"/web", nil), respond(404, requestStatusErrResponse))
+	server.register(GET, c.BuildEncodedURL(Tenants+"/hr", nil), respond(403, `{"errors":[]}`))
+
+	for tenant, expected := range map[string]bool{
+		"vsphere.local": true,
+		"finance":       true,
+		"web":           false,
+		"hr":            false,
+		"":              false,
+		"not a tenant":  false,
+	} {
+		isTenant, err := c.IsTenant(context.Background(), tenant)
+		utils.AssertNilError(t, err)
+		utils.AssertTrue(t, fmt.Sprintf("%s is a tenant: %v", tenant, expected), isTenant == expected)
+	}
+	// only the tenants which are not known are read
+	utils.AssertEqualsInt(t, 3, len(server.calls))
+
+	// the other errors are reported
+	server.register(GET, c.BuildEncodedURL(Tenants+"/finance", nil), respond(500, `{"errors":[]}`))
+	c.RetryPolicy.MaxRetries = 0
+	_, err := c.IsTenant(context.Background(), "finance")
+	utils.AssertNotNilError(t, err)
+}
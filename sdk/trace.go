@@ -0,0 +1,141 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RedactedValue replaces secrets in the traces of the requests
+const RedactedValue = "********"
+
+// redactedHeaders are never logged
+var redactedHeaders = []string{
+	AuthorizationHeader,
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// secretKeyParts are the parts of a property name which mark its value as a secret
+var secretKeyParts = []string{
+	"password",
+	"passwd",
+	"secret",
+	"privatekey",
+	"private_key",
+}
+
+// traceRequest logs the method, url, headers and body of the request
+func traceRequest(req *APIRequest, body []byte) {
+	headers := http.Header{}
+	for key, val := range req.Headers {
+		headers.Set(key, val)
+	}
+	log.Debug("vRA request: %s %s\n%s\n%s", req.Method, redactURL(req.URL), formatHeaders(headers), redactBody(body, false))
+}
+
+// traceResponse logs the status, headers and body of the response
+func traceResponse(req *APIRequest, resp *APIResponse) {
+	// the id of a new token is the token itself
+	isLogin := false
+	if u, err := url.Parse(req.URL); err == nil {
+		isLogin = req.Method == POST && strings.HasSuffix(u.Path, Tokens)
+	}
+	log.Debug("vRA response: %s %s: %s\n%s\n%s", req.Method, redactURL(req.URL), resp.Status, formatHeaders(resp.Headers), redactBody(resp.Body, isLogin))
+}
+
+// redactURL returns the url with the token redacted if it is the url of a token,
+// like the ones the tokens are validated with
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.Contains(u.Path, Tokens+"/") {
+		return rawURL
+	}
+	u.Path = u.Path[:strings.Index(u.Path, Tokens+"/")+len(Tokens)+1] + RedactedValue
+	// the stars are kept as they are rather than escaped
+	u.RawPath = u.Path
+	return u.String()
+}
+
+// formatHeaders returns the headers sorted by name, one per line, with the
+// credentials redacted
+func formatHeaders(headers http.Header) string {
+	var lines []string
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		for _, redacted := range redactedHeaders {
+			if strings.EqualFold(name, redacted) {
+				value = RedactedValue
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, value))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// redactBody returns the json body with the values of the properties that look like
+// secrets redacted. Bodies which are not json are returned as they are. If redactID is
+// set the top level id is redacted as well.
+func redactBody(body []byte, redactID bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	if object, ok := value.(map[string]interface{}); ok && redactID {
+		if _, ok := object["id"]; ok {
+			object["id"] = RedactedValue
+		}
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+// redactValue replaces the values of the secret properties of the json value
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if isSecretKey(key) {
+				v[key] = RedactedValue
+			} else if isSecretEntry(v) && key == "value" {
+				// a {"key": "password", "value": ...} entry of a resource or action template
+				v[key] = RedactedValue
+			} else {
+				v[key] = redactValue(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactValue(child)
+		}
+	}
+	return value
+}
+
+// isSecretKey returns true if the property name looks like the name of a password
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretEntry returns true for key/value pairs whose key looks like the name of a password
+func isSecretEntry(object map[string]interface{}) bool {
+	key, ok := object["key"].(string)
+	return ok && isSecretKey(key)
+}
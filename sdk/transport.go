@@ -0,0 +1,198 @@
+package sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// connection pool defaults
+const (
+	// DefaultMaxIdleConnsPerHost is the number of idle connections kept open to the vRA
+	// server, net/http only keeps 2 which is too few for parallel polls
+	DefaultMaxIdleConnsPerHost = 10
+	// DefaultIdleConnTimeout is how long an idle connection is kept open
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// ConnectionConfig holds the settings of the pool of persistent connections to the vRA server
+type ConnectionConfig struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept open, 0 means net/http's default
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the number of connections opened at the same time, 0 means no limit
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open, 0 means no limit
+	IdleConnTimeout time.Duration
+	// DisableKeepAlives closes the connection after every request, for the load
+	// balancers which do not handle persistent connections well
+	DisableKeepAlives bool
+}
+
+// ConfigureConnections replaces the connection pool settings of the transport of the client
+func (c *APIClient) ConfigureConnections(config ConnectionConfig) error {
+	transport, err := c.transport()
+	if err != nil {
+		return err
+	}
+	if config.MaxIdleConnsPerHost < 0 || config.MaxConnsPerHost < 0 || config.IdleConnTimeout < 0 {
+		return fmt.Errorf("The connection pool settings cannot be negative")
+	}
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = config.MaxConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	transport.DisableKeepAlives = config.DisableKeepAlives
+	transport.CloseIdleConnections()
+	return nil
+}
+
+// TLSConfig holds the TLS settings the client uses to connect to the vRA server
+type TLSConfig struct {
+	// Insecure disables the verification of the server certificate
+	Insecure bool
+	// CACertificates is a PEM bundle of certificate authorities trusted in addition to the system ones
+	CACertificates []byte
+	// ClientCertificate and ClientKey are the PEM encoded certificate and key the client
+	// authenticates with, they are optional
+	ClientCertificate []byte
+	ClientKey         []byte
+	// ServerName overrides the host name the server certificate is verified against
+	ServerName string
+}
+
+// ConfigureTLS replaces the TLS settings of the transport of the client
+func (c *APIClient) ConfigureTLS(config TLSConfig) error {
+	transport, err := c.transport()
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.Insecure,
+		ServerName:         config.ServerName,
+	}
+	if len(config.CACertificates) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(config.CACertificates) {
+			return fmt.Errorf("No PEM encoded certificate found in the CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(config.ClientCertificate) > 0 || len(config.ClientKey) > 0 {
+		certificate, err := tls.X509KeyPair(config.ClientCertificate, config.ClientKey)
+		if err != nil {
+			return fmt.Errorf("Invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	c.Insecure = config.Insecure
+	// the connections opened with the former settings are not reused
+	transport.CloseIdleConnections()
+	return nil
+}
+
+// ProxyConfig holds the proxy settings of the client
+type ProxyConfig struct {
+	// URL of the proxy, like http://proxy.example.com:3128
+	URL string
+	// Username and Password to authenticate to the proxy with, they are optional
+	Username string
+	Password string
+	// NoProxy lists the hosts which are connected to directly. An entry can be a
+	// host or domain name, which also matches its sub domains, an IP address, a CIDR
+	// range or "*", and may end with a port.
+	NoProxy []string
+}
+
+// ConfigureProxy makes the client connect to the vRA server through a proxy. If the
+// proxy URL is empty, the proxy is read from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+// environment variables, and the hosts of NoProxy are still connected to directly.
+func (c *APIClient) ConfigureProxy(config ProxyConfig) error {
+	transport, err := c.transport()
+	if err != nil {
+		return err
+	}
+	defer transport.CloseIdleConnections()
+	if config.URL == "" {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL, config.NoProxy) {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		}
+		return nil
+	}
+
+	proxyURL, err := url.Parse(config.URL)
+	if err != nil || proxyURL.Host == "" {
+		return fmt.Errorf("Invalid proxy URL %s", config.URL)
+	}
+	if config.Username != "" {
+		proxyURL.User = url.UserPassword(config.Username, config.Password)
+	}
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, config.NoProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+	return nil
+}
+
+// bypassProxy returns true if the host of the URL matches one of the noProxy entries
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if host == strings.TrimPrefix(entryHost, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entryHost, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// transport returns the transport of the http client, which NewClient cloned
+// from http.DefaultTransport so that it can be changed safely
+func (c *APIClient) transport() (*http.Transport, error) {
+	transport, ok := c.Client.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("Cannot configure a transport of type %T", c.Client.Transport)
+	}
+	return transport, nil
+}
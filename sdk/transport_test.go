@@ -0,0 +1,181 @@
+package sdk
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+func TestConfigureTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(requestStatusResponse))
+	}))
+	defer server.Close()
+
+	caCertificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	url := server.URL + ConsumerRequests + "/adca9535-4a35-4981-8864-28643bd990b0"
+
+	// the certificate of the server is not trusted
+	c := NewClient(mockUser, mockPassword, mockTenant, server.URL, false)
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MaxRetries = 0
+	_, err := c.Get(url, nil)
+	utils.AssertNotNilError(t, err)
+
+	err = c.ConfigureTLS(TLSConfig{CACertificates: caCertificate})
+	utils.AssertNilError(t, err)
+	_, err = c.Get(url, nil)
+	utils.AssertNilError(t, err)
+
+	// the test certificate is issued for example.com
+	err = c.ConfigureTLS(TLSConfig{CACertificates: caCertificate, ServerName: "example.com"})
+	utils.AssertNilError(t, err)
+	_, err = c.Get(url, nil)
+	utils.AssertNilError(t, err)
+
+	err = c.ConfigureTLS(TLSConfig{CACertificates: caCertificate, ServerName: "vra.example.org"})
+	utils.AssertNilError(t, err)
+	_, err = c.Get(url, nil)
+	utils.AssertNotNilError(t, err)
+
+	err = c.ConfigureTLS(TLSConfig{CACertificates: []byte("not a certificate")})
+	utils.AssertNotNilError(t, err)
+
+	err = c.ConfigureTLS(TLSConfig{ClientCertificate: caCertificate})
+	utils.AssertNotNilError(t, err)
+
+	// configuring a client never changes the default transport
+	insecureClient := NewClient(mockUser, mockPassword, mockTenant, server.URL, true)
+	utils.AssertNilError(t, insecureClient.ConfigureTLS(TLSConfig{Insecure: true}))
+	defaultTLSConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
+	utils.AssertTrue(t, "the default transport verifies certificates",
+		defaultTLSConfig == nil || !defaultTLSConfig.InsecureSkipVerify)
+}
+
+func TestConfigureProxy(t *testing.T) {
+	// a stand-in for the proxy, which answers the requests itself instead of forwarding them
+	var proxiedHosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("proxyuser:secret")) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		proxiedHosts = append(proxiedHosts, r.URL.Host)
+		w.Write([]byte(requestStatusResponse))
+	}))
+	defer proxy.Close()
+
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(requestStatusResponse))
+	}))
+	defer direct.Close()
+
+	c := NewClient(mockUser, mockPassword, mockTenant, "http://vra.example.com", false)
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MaxRetries = 0
+	path := ConsumerRequests + "/adca9535-4a35-4981-8864-28643bd990b0"
+
+	err := c.ConfigureProxy(ProxyConfig{
+		URL:      proxy.URL,
+		Username: "proxyuser",
+		Password: "secret",
+		NoProxy:  []string{"127.0.0.1", "internal.example.com"},
+	})
+	utils.AssertNilError(t, err)
+
+	_, err = c.Get("http://vra.example.com"+path, nil)
+	utils.AssertNilError(t, err)
+	_, err = c.Get(direct.URL+path, nil)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 1, len(proxiedHosts))
+	utils.AssertEqualsString(t, "vra.example.com", proxiedHosts[0])
+
+	// wrong credentials are rejected by the proxy
+	err = c.ConfigureProxy(ProxyConfig{URL: proxy.URL, Username: "proxyuser", Password: "wrong"})
+	utils.AssertNilError(t, err)
+	_, err = c.Get("http://vra.example.com"+path, nil)
+	utils.AssertNotNilError(t, err)
+
+	err = c.ConfigureProxy(ProxyConfig{URL: "not a url"})
+	utils.AssertNotNilError(t, err)
+
+	// the proxy of the environment is not used for the hosts of NoProxy either
+	err = c.ConfigureProxy(ProxyConfig{NoProxy: []string{"internal.example.com"}})
+	utils.AssertNilError(t, err)
+	transport, _ := c.transport()
+	req, _ := http.NewRequest(GET, "https://internal.example.com"+path, nil)
+	proxyURL, err := transport.Proxy(req)
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "internal.example.com is connected to directly", proxyURL == nil)
+	req, _ = http.NewRequest(GET, "https://vra.example.com"+path, nil)
+	proxyURL, err = transport.Proxy(req)
+	envProxyURL, envErr := http.ProxyFromEnvironment(req)
+	utils.AssertTrue(t, "the other hosts use the proxy of the environment",
+		fmt.Sprint(proxyURL) == fmt.Sprint(envProxyURL) && (err == nil) == (envErr == nil))
+}
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"internal.example.com", ".corp.example.org", "10.0.0.0/8", "192.168.1.10", "vra.example.net:8443"}
+	cases := map[string]bool{
+		"https://internal.example.com/api":     true,
+		"https://vra.internal.example.com/api": true,
+		"https://example.com/api":              false,
+		"https://vra.corp.example.org/api":     true,
+		"https://corp.example.org/api":         true,
+		"https://10.20.30.40/api":              true,
+		"https://11.20.30.40/api":              false,
+		"http://192.168.1.10:8080/api":         true,
+		"https://vra.example.net/api":          false,
+		"https://vra.example.net:8443/api":     true,
+	}
+	for rawURL, expected := range cases {
+		u, _ := url.Parse(rawURL)
+		utils.AssertTrue(t, rawURL, bypassProxy(u, noProxy) == expected)
+	}
+	u, _ := url.Parse("https://vra.example.com")
+	utils.AssertTrue(t, "* bypasses the proxy for all hosts", bypassProxy(u, []string{"*"}))
+}
+
+func TestConfigureConnections(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(requestStatusResponse))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	c := NewClient(mockUser, mockPassword, mockTenant, server.URL, false)
+	c.BearerToken = "Bearer mock-token"
+	url := server.URL + ConsumerRequests + "/adca9535-4a35-4981-8864-28643bd990b0"
+
+	// the connection is reused
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(url, nil)
+		utils.AssertNilError(t, err)
+	}
+	utils.AssertEqualsInt(t, 1, int(atomic.LoadInt32(&connections)))
+
+	err := c.ConfigureConnections(ConnectionConfig{DisableKeepAlives: true})
+	utils.AssertNilError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(url, nil)
+		utils.AssertNilError(t, err)
+	}
+	utils.AssertEqualsInt(t, 4, int(atomic.LoadInt32(&connections)))
+
+	err = c.ConfigureConnections(ConnectionConfig{MaxIdleConnsPerHost: -1})
+	utils.AssertNotNilError(t, err)
+}
@@ -0,0 +1,107 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+// AboutAPI describes the vRA appliance
+const AboutAPI = IdentityAPI + "/about"
+
+// About - the description of the vRA appliance returned by AboutAPI
+type About struct {
+	ReleaseVersion     string `json:"releaseVersion"`
+	BuildNumber        string `json:"buildNumber"`
+	BuildDate          string `json:"buildDate"`
+	ProductBuildNumber string `json:"productBuildNumber"`
+}
+
+// Version is the version of a vRA appliance
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// String formats the version like 7.5.0
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero returns true if the version is unknown
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// AtLeast returns true if the version is the same as or later than other
+func (v Version) AtLeast(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+// versionPattern matches the version at the start of a release version, like
+// 7.6.0 in "7.6.0 (build 13027280)" or 7.3 in "7.3.0.536"
+var versionPattern = regexp.MustCompile(`^\s*(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion parses the version out of the release version of a vRA appliance
+func ParseVersion(releaseVersion string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(releaseVersion)
+	if match == nil {
+		return Version{}, fmt.Errorf("Invalid vRA version %q", releaseVersion)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Patch, _ = strconv.Atoi(match[3])
+	}
+	return v, nil
+}
+
+// MinSupportedVersion and MaxSupportedMajor bound the versions of vRA the provider supports
+var (
+	MinSupportedVersion = Version{Major: 7}
+	MaxSupportedMajor   = 7
+)
+
+// GetAbout reads the description of the vRA appliance
+func (c *APIClient) GetAbout(ctx context.Context) (*About, error) {
+	url := c.BuildEncodedURL(AboutAPI, nil)
+	resp, err := c.GetWithContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var about About
+	if err := utils.UnmarshalJSON(resp.Body, &about); err != nil {
+		return nil, err
+	}
+	return &about, nil
+}
+
+// DetectVersion reads the version of the vRA appliance and keeps it in the Version of
+// the client. It returns an error if the provider does not support this version.
+func (c *APIClient) DetectVersion(ctx context.Context) (Version, error) {
+	about, err := c.GetAbout(ctx)
+	if err != nil {
+		return Version{}, err
+	}
+	version, err := ParseVersion(about.ReleaseVersion)
+	if err != nil {
+		return Version{}, err
+	}
+	log.Info("The vRA server is version %s, build %s", version, about.BuildNumber)
+	c.Version = version
+	if !version.AtLeast(MinSupportedVersion) || version.Major > MaxSupportedMajor {
+		return version, fmt.Errorf("The vRA server is version %s, the provider supports vRA %d.x from %s", version, MaxSupportedMajor, MinSupportedVersion)
+	}
+	return version, nil
+}
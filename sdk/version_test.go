@@ -0,0 +1,49 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+func TestParseVersion(t *testing.T) {
+	for releaseVersion, expected := range map[string]string{
+		"7.6.0 (build 13027280)": "7.6.0",
+		"7.3.0.536":              "7.3.0",
+		"7.5":                    "7.5.0",
+		" 7.4.1":                 "7.4.1",
+	} {
+		version, err := ParseVersion(releaseVersion)
+		utils.AssertNilError(t, err)
+		utils.AssertEqualsString(t, expected, version.String())
+	}
+
+	_, err := ParseVersion("unknown")
+	utils.AssertNotNilError(t, err)
+
+	v75 := Version{Major: 7, Minor: 5}
+	utils.AssertTrue(t, "7.5.0 >= 7.3.0", v75.AtLeast(Version{Major: 7, Minor: 3}))
+	utils.AssertTrue(t, "7.5.0 >= 7.5.0", v75.AtLeast(v75))
+	utils.AssertFalse(t, "7.5.0 >= 7.5.1", v75.AtLeast(Version{Major: 7, Minor: 5, Patch: 1}))
+	utils.AssertFalse(t, "7.5.0 >= 8.0.0", v75.AtLeast(Version{Major: 8}))
+}
+
+func TestDetectVersion(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	url := c.BuildEncodedURL(AboutAPI, nil)
+	server.register(GET, url, respond(200, aboutResponse))
+	version, err := c.DetectVersion(context.Background())
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "7.5.0", version.String())
+	utils.AssertEqualsString(t, "7.5.0", c.Version.String())
+
+	// the provider only supports vRA 7
+	server.register(GET, url, respond(200, `{"releaseVersion":"6.2.5"}`))
+	version, err = c.DetectVersion(context.Background())
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsString(t, "6.2.5", version.String())
+}
@@ -2,24 +2,34 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
-// NewClient creates a new APIClient object
-func NewClient(user, password, tenant, baseURL string, insecure bool) APIClient {
+// NewClient creates a new APIClient object. The client holds the token and the caches shared
+// by its requests, it is used through the returned pointer and must not be copied.
+func NewClient(user, password, tenant, baseURL string, insecure bool) *APIClient {
 
-	transport := http.DefaultTransport.(*http.Transport)
+	// the transport is cloned so that configuring the client never changes the
+	// settings of http.DefaultTransport, which is shared by the whole process
+	transport := http.DefaultTransport.(*http.Transport).Clone()
 	transport.TLSClientConfig = &tls.Config{
 		InsecureSkipVerify: insecure,
 	}
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
 	httpClient := &http.Client{
 		// Timeout:   clientTimeout,
 		Transport: transport,
 	}
-	apiClient := APIClient{
+	return &APIClient{
 		Username:    user,
 		Password:    password,
 		Tenant:      tenant,
@@ -27,32 +37,149 @@ func NewClient(user, password, tenant, baseURL string, insecure bool) APIClient
 		Insecure:    insecure,
 		BearerToken: "",
 		Client:      httpClient,
+		HTTPClient:  &NetHTTPClient{Client: httpClient},
+		RetryPolicy: DefaultRetryPolicy(),
 	}
-	return apiClient
 }
 
-// DoRequest makes the request and returns the response
-func (c *APIClient) DoRequest(req *APIRequest, login bool) (*APIResponse, error) {
-	r, err := FromAPIRequestToHTTPRequest(req)
+// DoRequest sends the request with the bearer token of the client, authenticating first
+// if needed, and returns the response. Responses with an error status are returned as an
+// *APIError.
+func (c *APIClient) DoRequest(req *APIRequest) (*APIResponse, error) {
+	body, err := readRequestBody(req)
 	if err != nil {
 		return nil, err
 	}
-	if !login {
-		c.Authenticate()
-		r.Header.Add(AuthorizationHeader, c.BearerToken)
+
+	token, err := c.getBearerToken(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+	idempotent := isIdempotent(req.Method)
+	resp, err := c.sendWithRetries(req, body, token, idempotent)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		log.Info("The bearer token was rejected by %v, authenticating again", req.URL)
+		token, err = c.getBearerToken(req.Context(), token)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.sendWithRetries(req, body, token, idempotent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkAPIResponse(req.Method, req.URL, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// readRequestBody reads the body of the request. The body is buffered so that the request
+// can be replayed if it has to be retried or the cached token has been revoked and the client
+// has to log in again.
+func readRequestBody(req *APIRequest) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	return ioutil.ReadAll(req.Body)
+}
+
+// sendWithRetries sends the request and sends it again, according to the retry policy
+// of the client, as long as it fails with a transient error
+func (c *APIClient) sendWithRetries(req *APIRequest, body []byte, token string, idempotent bool) (*APIResponse, error) {
+	for retry := 0; ; retry++ {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		resp, err := c.send(req, body, token)
+		if retry >= c.RetryPolicy.MaxRetries || req.Context().Err() != nil ||
+			!c.RetryPolicy.shouldRetry(idempotent, resp, err) {
+			return resp, err
+		}
+		wait := c.RetryPolicy.Backoff(retry)
+		log.Info("Retrying %v %v in %v, retry %d of %d", req.Method, redactURL(req.URL), wait, retry+1, c.RetryPolicy.MaxRetries)
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send sends a copy of the API request with the given body, and the authorization
+// header if a token is given, through the HTTPClient of the client
+func (c *APIClient) send(req *APIRequest, body []byte, token string) (*APIResponse, error) {
+	r := req.WithContext(req.Context())
+	r.Body = bytes.NewReader(body)
+	r.Headers = make(map[string]string, len(req.Headers)+1)
+	for key, val := range req.Headers {
+		r.Headers[key] = val
+	}
+	if token != "" {
+		r.AddHeader(AuthorizationHeader, token)
+	}
+	if c.Trace {
+		traceRequest(r, body)
 	}
-	r.Header.Add(ConnectionHeader, CloseConnection)
-	resp, err := c.Client.Do(r)
+	resp, err := c.HTTPClient.DoRequest(r)
 	if err != nil {
-		log.Error("An error occurred when calling %v on %v. Error: %v", req.Method, req.URL, err)
+		// the url of a token holds the token itself
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = redactURL(urlErr.URL)
+		}
+		log.Error("An error occurred when calling %v on %v. Error: %v", req.Method, redactURL(req.URL), err)
 		return nil, err
 	}
-	log.Info("Check the status of the request %s \n The response is: %s", req.URL, string(resp.Status))
-	return FromHTTPRespToAPIResp(resp)
+	log.Info("Check the status of the request %s \n The response is: %s", redactURL(req.URL), resp.Status)
+	if c.Trace {
+		traceResponse(r, resp)
+	}
+	return resp, nil
+}
+
+// getBearerToken returns the cached bearer token, authenticating first if there is
+// no token yet or it is about to expire. If rejectedToken is the token currently
+// cached, the server has refused it and a new one is requested.
+func (c *APIClient) getBearerToken(ctx context.Context, rejectedToken string) (string, error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+
+	if c.BearerToken != "" && c.BearerToken != rejectedToken && !c.tokenExpiring() {
+		return c.BearerToken, nil
+	}
+	if c.preIssuedToken {
+		return "", ErrTokenExpired
+	}
+	response, err := c.login(c.authenticationRequest(ctx))
+	if err != nil {
+		return "", err
+	}
+	c.setToken(response)
+	return c.BearerToken, nil
+}
+
+// tokenExpiring returns true if the cached token expires within TokenExpiryMargin.
+// A token without an expiry time is used until the server rejects it.
+func (c *APIClient) tokenExpiring() bool {
+	if c.TokenExpires.IsZero() {
+		return false
+	}
+	return time.Now().Add(TokenExpiryMargin).After(c.TokenExpires)
 }
 
 // Authenticate authenticates for the first time when the provider is invoked
 func (c *APIClient) Authenticate() error {
+	return c.AuthenticateWithContext(context.Background())
+}
+
+// AuthenticateWithContext is like Authenticate, but aborts when ctx is cancelled
+func (c *APIClient) AuthenticateWithContext(ctx context.Context) error {
+	return c.DoLogin(c.authenticationRequest(ctx))
+}
+
+// authenticationRequest returns the request which logs in with the credentials of the client
+func (c *APIClient) authenticationRequest(ctx context.Context) *APIRequest {
 	uri := fmt.Sprintf("%s"+Tokens, c.BaseURL)
 	data := AuthenticationRequest{
 		Username: c.Username,
@@ -66,25 +193,143 @@ func (c *APIClient) Authenticate() error {
 		Method: POST,
 		Body:   bytes.NewBufferString(string(jsonData)),
 		URL:    uri,
+		ctx:    ctx,
 	}
 	req.AddHeader(AcceptHeader, AppJSON)
 	req.AddHeader(ContentTypeHeader, AppJSON)
+	return req
+}
+
+// UseToken makes the client send the requests with a bearer token issued beforehand, instead of
+// logging in with its username and password. The token is checked against the server first. The
+// client cannot renew the token: once it has expired, the requests fail with ErrTokenExpired.
+func (c *APIClient) UseToken(ctx context.Context, token string) error {
+	token = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(token), "Bearer "))
+	if token == "" {
+		return fmt.Errorf("The bearer token is empty")
+	}
+
+	req := &APIRequest{
+		Method: HEAD,
+		URL:    fmt.Sprintf("%s"+Tokens+"/%s", c.BaseURL, token),
+		ctx:    ctx,
+	}
+	resp, err := c.sendWithRetries(req, nil, "", true)
+	if err != nil {
+		return err
+	}
+	if err := checkAPIResponse(req.Method, redactURL(req.URL), resp); err != nil {
+		if IsUnauthorized(err) || IsNotFound(err) {
+			return ErrTokenExpired
+		}
+		return err
+	}
+
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.BearerToken = fmt.Sprintf("Bearer %s", token)
+	c.TokenExpires = time.Time{}
+	c.preIssuedToken = true
+	return nil
+}
+
+// Logout revokes the bearer token the client logged in with, and the tokens of the clients
+// it has created for other tenants, so that the sessions are closed before the tokens
+// expire. The tokens are revoked concurrently, those which are not revoked before ctx is
+// done just expire. Tokens given to UseToken are left to their issuer.
+func (c *APIClient) Logout(ctx context.Context) error {
+	tenantsErr := make(chan error, 1)
+	go func() {
+		tenantsErr <- c.tenants.logout(ctx)
+	}()
+	err := c.logout(ctx)
+	if tenantErr := <-tenantsErr; err == nil {
+		err = tenantErr
+	}
+	return err
+}
+
+// logout revokes the bearer token of the client
+func (c *APIClient) logout(ctx context.Context) error {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+
+	if c.BearerToken == "" || c.preIssuedToken {
+		return nil
+	}
+	token := c.BearerToken
+	c.BearerToken = ""
+	c.TokenExpires = time.Time{}
 
-	return c.DoLogin(req)
+	req := &APIRequest{
+		Method: DELETE,
+		URL:    fmt.Sprintf("%s"+Tokens+"/%s", c.BaseURL, strings.TrimPrefix(token, "Bearer ")),
+		ctx:    ctx,
+	}
+	resp, err := c.sendWithRetries(req, nil, token, true)
+	if err != nil {
+		return err
+	}
+	err = checkAPIResponse(req.Method, redactURL(req.URL), resp)
+	if IsUnauthorized(err) || IsNotFound(err) {
+		// the token has already expired
+		return nil
+	}
+	return err
 }
 
 // DoLogin returns the bearer token
 func (c *APIClient) DoLogin(apiReq *APIRequest) error {
-	apiResp, err := c.DoRequest(apiReq, true)
+	response, err := c.login(apiReq)
 	if err != nil {
 		return err
 	}
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.setToken(response)
+	return nil
+}
+
+// login sends the login request and returns the token issued by the server, without
+// keeping it in the client
+func (c *APIClient) login(apiReq *APIRequest) (*AuthResponse, error) {
+	body, err := readRequestBody(apiReq)
+	if err != nil {
+		return nil, err
+	}
+	// asking for another token is harmless, so the login is retried like a GET
+	apiResp, err := c.sendWithRetries(apiReq, body, "", true)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAPIResponse(apiReq.Method, apiReq.URL, apiResp); err != nil {
+		return nil, err
+	}
 	response := &AuthResponse{}
 
 	err = json.Unmarshal(apiResp.Body, response)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	log.Info("Received a bearer token which expires at %v", response.Expires)
+	return response, nil
+}
+
+// setToken keeps the token issued by the server in the client, tokenLock must be held
+func (c *APIClient) setToken(response *AuthResponse) {
 	c.BearerToken = fmt.Sprintf("Bearer %s", response.ID)
-	return nil
+	c.TokenExpires = response.Expires
+}
+
+// sleep waits for the given duration, or returns the error of ctx if it is
+// cancelled before
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,347 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+// newMockClient returns a client that has not authenticated yet
+func newMockClient() *APIClient {
+	insecureBool, _ := strconv.ParseBool(mockInsecure)
+	return NewClient(mockUser, mockPassword, mockTenant, mockBaseURL, insecureBool)
+}
+
+func TestBearerTokenIsReused(t *testing.T) {
+	c := newMockClient()
+	server := newFakeServer(c)
+
+	tokenURL := fmt.Sprintf("%s"+Tokens, mockBaseURL)
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	server.register(POST, tokenURL, respond(200, validAuthResponse))
+	server.register(GET, url, respond(200, requestStatusResponse))
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GetRequestStatus(mockRequestID)
+		utils.AssertNilError(t, err)
+	}
+	utils.AssertEqualsInt(t, 1, server.callCount(POST, tokenURL))
+	utils.AssertEqualsInt(t, 3, server.callCount(GET, url))
+}
+
+func TestExpiringBearerTokenIsRenewed(t *testing.T) {
+	c := newMockClient()
+	server := newFakeServer(c)
+
+	tokenURL := fmt.Sprintf("%s"+Tokens, mockBaseURL)
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	server.register(POST, tokenURL, respond(200, expiredAuthResponse))
+	server.register(GET, url, respond(200, requestStatusResponse))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetRequestStatus(mockRequestID)
+		utils.AssertNilError(t, err)
+	}
+	utils.AssertEqualsInt(t, 2, server.callCount(POST, tokenURL))
+}
+
+func TestUnauthorizedRequestIsReplayed(t *testing.T) {
+	c := newMockClient()
+	server := newFakeServer(c)
+
+	tokenURL := fmt.Sprintf("%s"+Tokens, mockBaseURL)
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	server.register(POST, tokenURL, respond(200, validAuthResponse))
+
+	// the first GET is rejected, the replayed one succeeds
+	calls := 0
+	server.register(GET, url, func(req *APIRequest) (*APIResponse, error) {
+		calls++
+		if calls == 1 {
+			return respond(401, unauthorizedResponse)(req)
+		}
+		return respond(200, requestStatusResponse)(req)
+	})
+
+	requestStatus, err := c.GetRequestStatus(mockRequestID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "IN_PROGRESS", requestStatus.Phase)
+	utils.AssertEqualsInt(t, 2, server.callCount(POST, tokenURL))
+
+	// a request that is still rejected after logging in again is not replayed forever
+	server.reset()
+	server.register(POST, tokenURL, respond(200, validAuthResponse))
+	server.register(GET, url, respond(401, unauthorizedResponse))
+
+	requestStatus, err = c.GetRequestStatus(mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertNil(t, requestStatus)
+	utils.AssertEqualsInt(t, 2, server.callCount(GET, url))
+}
+
+func TestTransientFailuresAreRetried(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MinBackoff = time.Millisecond
+	server := newFakeServer(c)
+
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	calls := 0
+	server.register(GET, url, func(req *APIRequest) (*APIResponse, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		case 2:
+			return respond(503, "Service Unavailable")(req)
+		}
+		return respond(200, requestStatusResponse)(req)
+	})
+
+	requestStatus, err := c.GetRequestStatus(mockRequestID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "IN_PROGRESS", requestStatus.Phase)
+	utils.AssertEqualsInt(t, 3, calls)
+
+	// give up after MaxRetries
+	server.reset()
+	server.register(GET, url, respond(504, "Gateway Timeout"))
+	requestStatus, err = c.GetRequestStatus(mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertNil(t, requestStatus)
+	utils.AssertEqualsInt(t, c.RetryPolicy.MaxRetries+1, server.callCount(GET, url))
+
+	// non retryable status codes are returned right away
+	server.reset()
+	server.register(GET, url, respond(400, requestStatusErrResponse))
+	_, err = c.GetRequestStatus(mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, 1, server.callCount(GET, url))
+}
+
+func TestNonIdempotentRequestsAreNotReplayed(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MinBackoff = time.Millisecond
+	server := newFakeServer(c)
+
+	requestTemplate := &CatalogItemRequestTemplate{CatalogItemID: "feaedf73-560c-4612-a573-41667e017691"}
+	path := fmt.Sprintf(EntitledCatalogItems+"/"+"%s"+"/requests", requestTemplate.CatalogItemID)
+	url := c.BuildEncodedURL(path, nil)
+
+	server.register(POST, url, respond(503, "Service Unavailable"))
+	_, err := c.RequestCatalogItem(requestTemplate)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, 1, server.callCount(POST, url))
+
+	server.reset()
+	server.register(POST, url, failWith(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}))
+	_, err = c.RequestCatalogItem(requestTemplate)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, 1, server.callCount(POST, url))
+
+	// the request never reached the server, so it can be sent again
+	server.reset()
+	server.register(POST, url, failWith(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}))
+	_, err = c.RequestCatalogItem(requestTemplate)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, c.RetryPolicy.MaxRetries+1, server.callCount(POST, url))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	for retry := 0; retry < 10; retry++ {
+		wait := policy.Backoff(retry)
+		utils.AssertTrue(t, "backoff is capped", wait <= policy.MaxBackoff)
+		utils.AssertTrue(t, "backoff is positive", wait > 0)
+	}
+	utils.AssertTrue(t, "backoff grows", policy.Backoff(0) <= policy.MinBackoff)
+	utils.AssertTrue(t, "backoff grows", policy.Backoff(3) >= 4*policy.MinBackoff)
+}
+
+func TestCancelledRequestIsNotRetried(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MinBackoff = time.Hour
+	server := newFakeServer(c)
+
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+	server.register(GET, url, respond(503, "Service Unavailable"))
+
+	// the backoff before the retry is interrupted by the cancellation
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetRequestStatusWithContext(ctx, mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertTrue(t, "the request returns when the context is done", time.Since(start) < time.Minute)
+	utils.AssertEqualsString(t, context.DeadlineExceeded.Error(), err.Error())
+
+	// a request with a cancelled context is never sent
+	_, err = c.GetRequestStatusWithContext(ctx, mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, 1, server.callCount(GET, url))
+}
+
+func TestUseToken(t *testing.T) {
+	c := newMockClient()
+	server := newFakeServer(c)
+
+	tokenURL := fmt.Sprintf("%s"+Tokens, mockBaseURL)
+	mockToken := "MTU1MTEyMzE1NTc5ODozZDhmY2U3OTc1NzZhMGRmNDY2ZDp0ZW5hbnQ6cWV1c2"
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+
+	server.register(HEAD, tokenURL+"/"+mockToken, respond(204, ""))
+	server.register(GET, url, func(req *APIRequest) (*APIResponse, error) {
+		if req.Headers[AuthorizationHeader] != "Bearer "+mockToken {
+			return respond(401, unauthorizedResponse)(req)
+		}
+		return respond(200, requestStatusResponse)(req)
+	})
+
+	err := c.UseToken(context.Background(), "Bearer "+mockToken+"\n")
+	utils.AssertNilError(t, err)
+	requestStatus, err := c.GetRequestStatus(mockRequestID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "IN_PROGRESS", requestStatus.Phase)
+	utils.AssertEqualsInt(t, 0, server.callCount(POST, tokenURL))
+
+	// an expired token is never renewed with the username and password
+	server.register(GET, url, respond(401, unauthorizedResponse))
+	_, err = c.GetRequestStatus(mockRequestID)
+	utils.AssertEqualsString(t, ErrTokenExpired.Error(), err.Error())
+	utils.AssertEqualsInt(t, 0, server.callCount(POST, tokenURL))
+
+	// the token is checked before it is used
+	server.register(HEAD, tokenURL+"/expired", respond(401, unauthorizedResponse))
+	err = c.UseToken(context.Background(), "expired")
+	utils.AssertEqualsString(t, ErrTokenExpired.Error(), err.Error())
+
+	err = c.UseToken(context.Background(), " ")
+	utils.AssertNotNilError(t, err)
+}
+
+func TestLogout(t *testing.T) {
+	c := newMockClient()
+	server := newFakeServer(c)
+
+	tokenURL := fmt.Sprintf("%s"+Tokens, mockBaseURL)
+	// the id of the token in validAuthResponse
+	mockToken := "MTU1MTEyMzE1NTc5ODpiYTZkYjdhNjZlNGNkYjZmZTBiMjp0ZW5hbnQ6cWV1c2VybmFtZTpmcml0ekBjb2tlLnNxYS1ob3Jpem9uLmxvY2Fs"
+	server.register(POST, tokenURL, respond(200, validAuthResponse))
+	server.register(DELETE, tokenURL+"/"+mockToken, func(req *APIRequest) (*APIResponse, error) {
+		if req.Headers[AuthorizationHeader] != "Bearer "+mockToken {
+			return respond(401, unauthorizedResponse)(req)
+		}
+		return respond(204, "")(req)
+	})
+
+	err := c.Authenticate()
+	utils.AssertNilError(t, err)
+	err = c.Logout(context.Background())
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "", c.BearerToken)
+	utils.AssertEqualsInt(t, 1, server.callCount(DELETE, tokenURL+"/"+mockToken))
+
+	// there is no session left to close
+	err = c.Logout(context.Background())
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 1, server.callCount(DELETE, tokenURL+"/"+mockToken))
+
+	// a token which has already expired is not an error
+	c.BearerToken = "Bearer expired"
+	server.register(DELETE, tokenURL+"/expired", respond(401, unauthorizedResponse))
+	err = c.Logout(context.Background())
+	utils.AssertNilError(t, err)
+
+	// a pre-issued token is not revoked
+	server.register(HEAD, tokenURL+"/"+mockToken, respond(204, ""))
+	err = c.UseToken(context.Background(), mockToken)
+	utils.AssertNilError(t, err)
+	err = c.Logout(context.Background())
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "Bearer "+mockToken, c.BearerToken)
+	utils.AssertEqualsInt(t, 1, server.callCount(DELETE, tokenURL+"/"+mockToken))
+}
+
+func TestLogoutRevokesTokensConcurrently(t *testing.T) {
+	c := newMockClient()
+	// every revocation is held until all of them have been sent
+	var (
+		lock    sync.Mutex
+		revoked []string
+	)
+	allSent := make(chan struct{})
+	c.HTTPClient = HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+		lock.Lock()
+		revoked = append(revoked, req.URL)
+		if len(revoked) == 3 {
+			close(allSent)
+		}
+		lock.Unlock()
+		select {
+		case <-allSent:
+			return &APIResponse{Status: "204 No Content", StatusCode: 204}, nil
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	})
+	c.BearerToken = "Bearer provider"
+	for _, tenant := range []string{"finance", "sales"} {
+		tenantClient, err := c.ForTenant(tenant)
+		utils.AssertNilError(t, err)
+		tenantClient.BearerToken = "Bearer " + tenant
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := c.Logout(ctx)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 3, len(revoked))
+	utils.AssertEqualsString(t, "", c.BearerToken)
+}
+
+func TestAuthenticateTakesTheTokenLock(t *testing.T) {
+	c := newMockClient()
+	c.HTTPClient = HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+		body := requestStatusResponse
+		if req.Method == POST {
+			body = validAuthResponse
+		}
+		return &APIResponse{Status: "200 OK", StatusCode: 200, Body: []byte(body)}, nil
+	})
+
+	// logging in again while requests read the token is safe, which the race detector checks
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			utils.AssertNilError(t, c.Authenticate())
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := c.GetRequestStatus("adca9535-4a35-4981-8864-28643bd990b0")
+			utils.AssertNilError(t, err)
+		}()
+	}
+	wg.Wait()
+	utils.AssertContainsString(t, "Bearer ", c.BearerToken)
+}
@@ -1,8 +1,8 @@
 package sdk
 
 import (
+	"context"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/vmware/terraform-provider-vra7/utils"
@@ -24,6 +24,7 @@ const (
 	PostActionTemplateAPI       = ConsumerResources + "/" + "%s" + "/actions/" + "%s" + "/requests"
 	GetActionTemplateAPI        = PostActionTemplateAPI + "/template"
 	GetRequestResourceViewAPI   = ConsumerRequests + "/" + "%s" + "/resourceViews"
+	CancelRequestAPI            = ConsumerRequests + "/" + "%s" + "/cancel"
 	RequestTemplateAPI          = EntitledCatalogItems + "/" + "%s" + "/requests/template"
 
 	// read resource machine constants
@@ -47,39 +48,72 @@ const (
 	InProgress             = "IN_PROGRESS"
 	Successful             = "SUCCESSFUL"
 	Failed                 = "FAILED"
+	Rejected               = "REJECTED"
 	Submitted              = "SUBMITTED"
 	InfrastructureVirtual  = "Infrastructure.Virtual"
 	DeploymentResourceType = "composition.resource.type.deployment"
 	Component              = "Component"
 	Reconfigure            = "Reconfigure"
 	Destroy                = "Destroy"
+
+	AmbiguousNameError = "There are several %s with the name %s (ids: %s), use the id instead"
 )
 
 // GetCatalogItemRequestTemplate - Call to retrieve a request template for a catalog item.
+// The template is cached for the lifetime of the client, every call returns a new copy.
 func (c *APIClient) GetCatalogItemRequestTemplate(catalogItemID string) (*CatalogItemRequestTemplate, error) {
+	return c.GetCatalogItemRequestTemplateWithContext(context.Background(), catalogItemID)
+}
 
-	// Form a path to read catalog request template via REST call
-	path := fmt.Sprintf(RequestTemplateAPI, catalogItemID)
-	url := c.BuildEncodedURL(path, nil)
-	resp, respErr := c.Get(url, nil)
-	if respErr != nil {
-		return nil, respErr
+// GetCatalogItemRequestTemplateWithContext is like GetCatalogItemRequestTemplate, but aborts when ctx is cancelled
+func (c *APIClient) GetCatalogItemRequestTemplateWithContext(ctx context.Context, catalogItemID string) (*CatalogItemRequestTemplate, error) {
+
+	// The body is cached rather than the template, the callers get their own copy to fill in
+	body, err := c.lookups.do(ctx, "requestTemplate/"+catalogItemID, func() (interface{}, error) {
+		// Form a path to read catalog request template via REST call
+		path := fmt.Sprintf(RequestTemplateAPI, catalogItemID)
+		url := c.BuildEncodedURL(path, nil)
+		resp, respErr := c.GetWithContext(ctx, url, nil)
+		if respErr != nil {
+			return nil, respErr
+		}
+		return resp.Body, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	var requestTemplate CatalogItemRequestTemplate
-	unmarshallErr := utils.UnmarshalJSON(resp.Body, &requestTemplate)
+	unmarshallErr := utils.UnmarshalJSON(body.([]byte), &requestTemplate)
 	if unmarshallErr != nil {
 		return nil, unmarshallErr
 	}
 	return &requestTemplate, nil
 }
 
-// ReadCatalogItemNameByID - This function returns the catalog item name using catalog item ID
+// ReadCatalogItemNameByID - This function returns the catalog item name using catalog item ID.
+// The name is cached for the lifetime of the client.
 func (c *APIClient) ReadCatalogItemNameByID(catalogItemID string) (string, error) {
+	return c.ReadCatalogItemNameByIDWithContext(context.Background(), catalogItemID)
+}
+
+// ReadCatalogItemNameByIDWithContext is like ReadCatalogItemNameByID, but aborts when ctx is cancelled
+func (c *APIClient) ReadCatalogItemNameByIDWithContext(ctx context.Context, catalogItemID string) (string, error) {
+	value, err := c.lookups.do(ctx, "catalogItemName/"+catalogItemID, func() (interface{}, error) {
+		return c.readCatalogItemNameByID(ctx, catalogItemID)
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// readCatalogItemNameByID reads the catalog item name from the server
+func (c *APIClient) readCatalogItemNameByID(ctx context.Context, catalogItemID string) (string, error) {
 
 	path := fmt.Sprintf(EntitledCatalogItems+"/"+"%s", catalogItemID)
 	url := c.BuildEncodedURL(path, nil)
-	resp, respErr := c.Get(url, nil)
+	resp, respErr := c.GetWithContext(ctx, url, nil)
 	if respErr != nil {
 		return "", respErr
 	}
@@ -92,90 +126,149 @@ func (c *APIClient) ReadCatalogItemNameByID(catalogItemID string) (string, error
 	return response.CatalogItem.Name, nil
 }
 
-// ReadCatalogItemByName to read id of catalog from vRA using catalog_name
+// ReadCatalogItemByName to read id of catalog from vRA using catalog_name.
+// The id is cached for the lifetime of the client.
 func (c *APIClient) ReadCatalogItemByName(catalogName string) (string, error) {
+	return c.ReadCatalogItemByNameWithContext(context.Background(), catalogName)
+}
 
-	// reading the first page to get the total number of pages
-	entitledCatalogItems, err := c.readCatalogItemsByPage(1)
+// ReadCatalogItemByNameWithContext is like ReadCatalogItemByName, but aborts when ctx is cancelled
+func (c *APIClient) ReadCatalogItemByNameWithContext(ctx context.Context, catalogName string) (string, error) {
+	value, err := c.lookups.do(ctx, "catalogItemID/"+catalogName, func() (interface{}, error) {
+		return c.readCatalogItemByName(ctx, catalogName)
+	})
 	if err != nil {
 		return "", err
 	}
+	return value.(string), nil
+}
 
-	for page := 1; page <= entitledCatalogItems.Metadata.TotalPages; page++ {
-		entitledCatalogItemViews, err := c.readCatalogItemsByPage(page)
-		if err != nil {
-			return "", err
-		}
-		catalogItemsArray := entitledCatalogItemViews.Content.([]interface{})
-		for i := range catalogItemsArray {
-			catalogItem := catalogItemsArray[i].(map[string]interface{})
-			name := catalogItem["name"].(string)
-			if name == catalogName {
-				return catalogItem["catalogItemId"].(string), nil
-			}
+// readCatalogItemByName looks the catalog item up on the server by its name
+func (c *APIClient) readCatalogItemByName(ctx context.Context, catalogName string) (string, error) {
+
+	filter := map[string]string{
+		FilterQueryParam: ODataEquals("name", catalogName),
+	}
+	catalogItems, err := c.ListEntitledCatalogItemViews(ctx, filter)
+	if err != nil {
+		return "", err
+	}
+	var catalogItemIDs []string
+	for _, catalogItem := range catalogItems {
+		if catalogItem.Name == catalogName {
+			catalogItemIDs = append(catalogItemIDs, catalogItem.CatalogItemID)
 		}
+	}
+	if len(catalogItemIDs) == 0 {
+		return "", fmt.Errorf("Catalog item, %s not found", catalogName)
+	}
+	if len(catalogItemIDs) > 1 {
+		return "", fmt.Errorf(AmbiguousNameError, "catalog items", catalogName, strings.Join(catalogItemIDs, ", "))
+	}
+	return catalogItemIDs[0], nil
+}
 
+// ListEntitledCatalogItemViews returns the catalog items the current user is entitled to, of
+// all the pages. The query parameters are sent with every page, like an OData filter.
+func (c *APIClient) ListEntitledCatalogItemViews(ctx context.Context, queryParameters map[string]string) ([]EntitledCatalogItemView, error) {
+	var catalogItems []EntitledCatalogItemView
+	err := c.ForEachPage(ctx, EntitledCatalogItemViewsAPI, queryParameters, DefaultPageSize, func(page *Page) (bool, error) {
+		var content []EntitledCatalogItemView
+		if err := page.UnmarshalContent(&content); err != nil {
+			return false, err
+		}
+		catalogItems = append(catalogItems, content...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return "", fmt.Errorf("Catalog item, %s not found", catalogName)
+	return catalogItems, nil
 }
 
-// ReadCatalogItemsByPage return catalogItems by page
-func (c *APIClient) readCatalogItemsByPage(i int) (*EntitledCatalogItemViews, error) {
-	url := c.BuildEncodedURL(EntitledCatalogItemViewsAPI, map[string]string{
-		"page": strconv.Itoa(i)})
-	resp, respErr := c.Get(url, nil)
-	if respErr != nil || resp.StatusCode != 200 {
+// GetEntitledCatalogItemView returns the catalog item with the given id, if the current
+// user is entitled to it
+func (c *APIClient) GetEntitledCatalogItemView(ctx context.Context, catalogItemID string) (*EntitledCatalogItemView, error) {
+	url := c.BuildEncodedURL(EntitledCatalogItemViewsAPI+"/"+catalogItemID, nil)
+	resp, respErr := c.GetWithContext(ctx, url, nil)
+	if respErr != nil {
 		return nil, respErr
 	}
 
-	var template EntitledCatalogItemViews
-	unmarshallErr := utils.UnmarshalJSON(resp.Body, &template)
+	var catalogItem EntitledCatalogItemView
+	unmarshallErr := utils.UnmarshalJSON(resp.Body, &catalogItem)
 	if unmarshallErr != nil {
 		return nil, unmarshallErr
 	}
-
-	return &template, nil
+	return &catalogItem, nil
 }
 
-// GetBusinessGroupID retrieves business group id from business group name
+// GetBusinessGroupID retrieves business group id from business group name.
+// The id is cached for the lifetime of the client.
 func (c *APIClient) GetBusinessGroupID(businessGroupName string, tenant string) (string, error) {
+	return c.GetBusinessGroupIDWithContext(context.Background(), businessGroupName, tenant)
+}
 
-	path := Tenants + "/" + tenant + "/subtenants"
+// GetBusinessGroupIDWithContext is like GetBusinessGroupID, but aborts when ctx is cancelled
+func (c *APIClient) GetBusinessGroupIDWithContext(ctx context.Context, businessGroupName string, tenant string) (string, error) {
+	value, err := c.lookups.do(ctx, "businessGroupID/"+tenant+"/"+businessGroupName, func() (interface{}, error) {
+		return c.getBusinessGroupID(ctx, businessGroupName, tenant)
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
 
-	log.Info("Fetching business group id from name..GET %s ", path)
+// getBusinessGroupID looks the business group up on the server by its name
+func (c *APIClient) getBusinessGroupID(ctx context.Context, businessGroupName string, tenant string) (string, error) {
 
-	url := c.BuildEncodedURL(path, nil)
+	path := Tenants + "/" + tenant + "/subtenants"
 
-	resp, respErr := c.Get(url, nil)
-	if respErr != nil {
-		return "", respErr
-	}
+	log.Info("Fetching business group id from name..GET %s ", path)
 
-	var businessGroups BusinessGroups
-	unmarshallErr := utils.UnmarshalJSON(resp.Body, &businessGroups)
-	if unmarshallErr != nil {
-		return "", unmarshallErr
+	filter := map[string]string{
+		FilterQueryParam: ODataEquals("name", businessGroupName),
 	}
-	// BusinessGroups array will contain only one BusinessGroup element containing the BG
-	// with the name businessGroupName.
-	// Fetch the id of that
-	for _, businessGroup := range businessGroups.Content {
-		if businessGroup.Name == businessGroupName {
-			log.Info("Found the business group id of the group %s: %s ", businessGroupName, businessGroup.ID)
-			return businessGroup.ID, nil
+	var businessGroupIDs []string
+	err := c.ForEachPage(ctx, path, filter, DefaultPageSize, func(page *Page) (bool, error) {
+		var businessGroups []BusinessGroup
+		if err := page.UnmarshalContent(&businessGroups); err != nil {
+			return false, err
 		}
+		for _, businessGroup := range businessGroups {
+			if businessGroup.Name == businessGroupName {
+				log.Info("Found the business group id of the group %s: %s ", businessGroupName, businessGroup.ID)
+				businessGroupIDs = append(businessGroupIDs, businessGroup.ID)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(businessGroupIDs) == 0 {
+		log.Errorf("No business group found with name: %s ", businessGroupName)
+		return "", fmt.Errorf("No business group found with name: %s ", businessGroupName)
 	}
-	log.Errorf("No business group found with name: %s ", businessGroupName)
-	return "", fmt.Errorf("No business group found with name: %s ", businessGroupName)
+	if len(businessGroupIDs) > 1 {
+		return "", fmt.Errorf(AmbiguousNameError, "business groups", businessGroupName, strings.Join(businessGroupIDs, ", "))
+	}
+	return businessGroupIDs[0], nil
 }
 
 // GetRequestStatus - To read request status of resource
 // which is used to show information to user post create call.
 func (c *APIClient) GetRequestStatus(requestID string) (*RequestStatusView, error) {
+	return c.GetRequestStatusWithContext(context.Background(), requestID)
+}
+
+// GetRequestStatusWithContext is like GetRequestStatus, but aborts when ctx is cancelled
+func (c *APIClient) GetRequestStatusWithContext(ctx context.Context, requestID string) (*RequestStatusView, error) {
 	//Form a URL to read request status
 	path := fmt.Sprintf(ConsumerRequests+"/"+"%s", requestID)
 	url := c.BuildEncodedURL(path, nil)
-	resp, respErr := c.Get(url, nil)
+	resp, respErr := c.GetWithContext(ctx, url, nil)
 	if respErr != nil {
 		return nil, respErr
 	}
@@ -188,32 +281,121 @@ func (c *APIClient) GetRequestStatus(requestID string) (*RequestStatusView, erro
 	return &response, nil
 }
 
+// GetRequest reads a catalog request, with the catalog item, the business group and
+// the data it was requested with
+func (c *APIClient) GetRequest(ctx context.Context, requestID string) (*CatalogRequest, error) {
+	path := fmt.Sprintf(ConsumerRequests+"/"+"%s", requestID)
+	url := c.BuildEncodedURL(path, nil)
+	resp, err := c.GetWithContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var request CatalogRequest
+	if err := utils.UnmarshalJSON(resp.Body, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetResource reads a provisioned resource, like a deployment or a machine
+func (c *APIClient) GetResource(ctx context.Context, resourceID string) (*ResourceActionContent, error) {
+	path := ConsumerResources + "/" + resourceID
+	url := c.BuildEncodedURL(path, nil)
+	resp, err := c.GetWithContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource ResourceActionContent
+	if err := utils.UnmarshalJSON(resp.Body, &resource); err != nil {
+		return nil, err
+	}
+	return &resource, nil
+}
+
+// FindDeploymentByName looks up the deployment with the given name among the resources
+// the user is entitled to
+func (c *APIClient) FindDeploymentByName(ctx context.Context, name string) (*ResourceActionContent, error) {
+	filter := map[string]string{
+		FilterQueryParam: ODataEquals("name", name),
+	}
+	var deployments []ResourceActionContent
+	err := c.ForEachPage(ctx, ConsumerResources, filter, DefaultPageSize, func(page *Page) (bool, error) {
+		var resources []ResourceActionContent
+		if err := page.UnmarshalContent(&resources); err != nil {
+			return false, err
+		}
+		for _, resource := range resources {
+			if resource.Name == name && resource.ResourceTypeRef.ID == DeploymentResourceType {
+				deployments = append(deployments, resource)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments) == 0 {
+		return nil, fmt.Errorf("No deployment found with name: %s", name)
+	}
+	if len(deployments) > 1 {
+		var ids []string
+		for _, deployment := range deployments {
+			ids = append(ids, deployment.ID)
+		}
+		return nil, fmt.Errorf(AmbiguousNameError, "deployments", name, strings.Join(ids, ", "))
+	}
+	return &deployments[0], nil
+}
+
+// CancelRequest cancels a catalog request which has not completed yet. A request which is
+// already provisioning may not be cancellable anymore, vRA then rejects the cancellation.
+func (c *APIClient) CancelRequest(ctx context.Context, requestID string) error {
+	path := fmt.Sprintf(CancelRequestAPI, requestID)
+	url := c.BuildEncodedURL(path, nil)
+	_, err := c.PostWithContext(ctx, url, nil, nil)
+	return err
+}
+
 // GetRequestResourceView retrieves the resources that were provisioned as a result of a given request.
 func (c *APIClient) GetRequestResourceView(catalogRequestID string) (*RequestResourceView, error) {
+	return c.GetRequestResourceViewWithContext(context.Background(), catalogRequestID)
+}
+
+// GetRequestResourceViewWithContext is like GetRequestResourceView, but aborts when ctx is cancelled
+func (c *APIClient) GetRequestResourceViewWithContext(ctx context.Context, catalogRequestID string) (*RequestResourceView, error) {
 	path := fmt.Sprintf(GetRequestResourceViewAPI, catalogRequestID)
-	url := c.BuildEncodedURL(path, nil)
-	resp, respErr := c.Get(url, nil)
-	if respErr != nil {
-		return nil, respErr
-	}
 
 	var response RequestResourceView
-	unmarshallErr := utils.UnmarshalJSON(resp.Body, &response)
-	if unmarshallErr != nil {
-		return nil, unmarshallErr
+	err := c.ForEachPage(ctx, path, nil, DefaultPageSize, func(page *Page) (bool, error) {
+		var content []DeploymentResource
+		if err := page.UnmarshalContent(&content); err != nil {
+			return false, err
+		}
+		response.Content = append(response.Content, content...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return &response, nil
 }
 
 // RequestCatalogItem - Make a catalog request.
 func (c *APIClient) RequestCatalogItem(requestTemplate *CatalogItemRequestTemplate) (*CatalogRequest, error) {
+	return c.RequestCatalogItemWithContext(context.Background(), requestTemplate)
+}
+
+// RequestCatalogItemWithContext is like RequestCatalogItem, but aborts when ctx is cancelled
+func (c *APIClient) RequestCatalogItemWithContext(ctx context.Context, requestTemplate *CatalogItemRequestTemplate) (*CatalogRequest, error) {
 	//Form a path to set a REST call to create a machine
 	path := fmt.Sprintf(EntitledCatalogItems+"/"+"%s"+
 		"/requests", requestTemplate.CatalogItemID)
 
 	buffer, _ := utils.MarshalToJSON(requestTemplate)
 	url := c.BuildEncodedURL(path, nil)
-	resp, respErr := c.Post(url, buffer, nil)
+	resp, respErr := c.PostWithContext(ctx, url, buffer, nil)
 	if respErr != nil {
 		return nil, respErr
 	}
@@ -228,28 +410,39 @@ func (c *APIClient) RequestCatalogItem(requestTemplate *CatalogItemRequestTempla
 
 // GetResourceActions get the resource actions allowed for a resource
 func (c *APIClient) GetResourceActions(catalogItemRequestID string) (*ResourceActions, error) {
-	path := fmt.Sprintf(GetResourceAPI, catalogItemRequestID)
+	return c.GetResourceActionsWithContext(context.Background(), catalogItemRequestID)
+}
 
-	url := c.BuildEncodedURL(path, nil)
-	resp, respErr := c.Get(url, nil)
-	if respErr != nil {
-		return nil, respErr
-	}
+// GetResourceActionsWithContext is like GetResourceActions, but aborts when ctx is cancelled
+func (c *APIClient) GetResourceActionsWithContext(ctx context.Context, catalogItemRequestID string) (*ResourceActions, error) {
+	path := fmt.Sprintf(GetResourceAPI, catalogItemRequestID)
 
 	var resourceActions ResourceActions
-	unmarshallErr := utils.UnmarshalJSON(resp.Body, &resourceActions)
-	if unmarshallErr != nil {
-		return nil, unmarshallErr
+	err := c.ForEachPage(ctx, path, nil, DefaultPageSize, func(page *Page) (bool, error) {
+		var content []ResourceActionContent
+		if err := page.UnmarshalContent(&content); err != nil {
+			return false, err
+		}
+		resourceActions.Content = append(resourceActions.Content, content...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return &resourceActions, nil
 }
 
 // GetResourceActionTemplate get the action template corresponding to the action id
 func (c *APIClient) GetResourceActionTemplate(resourceID, actionID string) (*ResourceActionTemplate, error) {
+	return c.GetResourceActionTemplateWithContext(context.Background(), resourceID, actionID)
+}
+
+// GetResourceActionTemplateWithContext is like GetResourceActionTemplate, but aborts when ctx is cancelled
+func (c *APIClient) GetResourceActionTemplateWithContext(ctx context.Context, resourceID, actionID string) (*ResourceActionTemplate, error) {
 	getActionTemplatePath := fmt.Sprintf(GetActionTemplateAPI, resourceID, actionID)
 	log.Info("Call GET to fetch the reconfigure action template %v ", getActionTemplatePath)
 	url := c.BuildEncodedURL(getActionTemplatePath, nil)
-	resp, respErr := c.Get(url, nil)
+	resp, respErr := c.GetWithContext(ctx, url, nil)
 	if respErr != nil {
 		return nil, respErr
 	}
@@ -264,11 +457,16 @@ func (c *APIClient) GetResourceActionTemplate(resourceID, actionID string) (*Res
 
 // PostResourceAction updates the resource
 func (c *APIClient) PostResourceAction(resourceID, actionID string, resourceActionTemplate *ResourceActionTemplate) (string, error) {
+	return c.PostResourceActionWithContext(context.Background(), resourceID, actionID, resourceActionTemplate)
+}
+
+// PostResourceActionWithContext is like PostResourceAction, but aborts when ctx is cancelled
+func (c *APIClient) PostResourceActionWithContext(ctx context.Context, resourceID, actionID string, resourceActionTemplate *ResourceActionTemplate) (string, error) {
 
 	postActionTemplatePath := fmt.Sprintf(PostActionTemplateAPI, resourceID, actionID)
 	buffer, _ := utils.MarshalToJSON(resourceActionTemplate)
 	url := c.BuildEncodedURL(postActionTemplatePath, nil)
-	resp, respErr := c.Post(url, buffer, nil)
+	resp, respErr := c.PostWithContext(ctx, url, buffer, nil)
 	if respErr != nil || resp.StatusCode != 201 {
 		return "", respErr
 	}
@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -11,7 +12,7 @@ import (
 )
 
 var (
-	client       APIClient
+	client       *APIClient
 	mockUser     = os.Getenv("VRA7_USERNAME")
 	mockPassword = os.Getenv("VRA7_PASSWORD")
 	mockTenant   = os.Getenv("VRA7_TENANT")
@@ -23,6 +24,8 @@ func init() {
 	fmt.Println("init")
 	insecureBool, _ := strconv.ParseBool(mockInsecure)
 	client = NewClient(mockUser, mockPassword, mockTenant, mockBaseURL, insecureBool)
+	// use a cached token so that the tests do not need to mock the login call
+	client.BearerToken = "Bearer mock-token"
 }
 
 func TestGetCatalogItemRequestTemplate(t *testing.T) {
@@ -81,7 +84,7 @@ func TestReadCatalogItemByName(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 
 	url := client.BuildEncodedURL(EntitledCatalogItemViewsAPI, map[string]string{
-		"page": "1"})
+		"page": "1", "limit": "20", "$filter": "name eq 'CentOs'"})
 
 	httpmock.RegisterResponder("GET", url,
 		httpmock.NewStringResponder(200, entitledCatalogItemViewsResponse))
@@ -89,9 +92,80 @@ func TestReadCatalogItemByName(t *testing.T) {
 	catalogItemID, err := client.ReadCatalogItemByName("CentOs")
 	utils.AssertEqualsString(t, "feaedf73-560c-4612-a573-41667e017691", catalogItemID)
 	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 1, httpmock.GetCallCountInfo()["GET "+url])
 
+	httpmock.Reset()
+	url = client.BuildEncodedURL(EntitledCatalogItemViewsAPI, nil)
+	httpmock.RegisterResponder("GET", url,
+		httpmock.NewStringResponder(200, entitledCatalogItemViewsResponse))
 	catalogItemID, err = client.ReadCatalogItemByName("Invalid Catalog Item name")
 	utils.AssertEqualsString(t, "", catalogItemID)
+	utils.AssertNotNilError(t, err)
+
+	// several catalog items with the same name, the id found above is cached
+	client.ClearLookupCache()
+	httpmock.Reset()
+	httpmock.RegisterResponder("GET", url,
+		httpmock.NewStringResponder(200, duplicateEntitledCatalogItemViewsResponse))
+	catalogItemID, err = client.ReadCatalogItemByName("CentOs")
+	utils.AssertEqualsString(t, "", catalogItemID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertContainsString(t, "several catalog items with the name CentOs", err.Error())
+}
+
+func TestListEntitledCatalogItemViews(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	url := c.BuildEncodedURL(EntitledCatalogItemViewsAPI, nil)
+	server.register(GET, url, respond(200, entitledCatalogItemViewsResponse))
+
+	catalogItems, err := c.ListEntitledCatalogItemViews(context.Background(), nil)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 4, len(catalogItems))
+	catalogItem := catalogItems[2]
+	utils.AssertEqualsString(t, "1eb8e1d4-152e-4a93-a3b6-265df6870555", catalogItem.CatalogItemID)
+	utils.AssertEqualsString(t, "Azure Machine (2)", catalogItem.Name)
+	utils.AssertEqualsString(t, "test-service", catalogItem.ServiceRef.Label)
+	utils.AssertEqualsString(t, "com.vmware.csp.core.designer.service.serviceblueprint", catalogItem.CatalogItemTypeRef.ID)
+	utils.AssertEqualsString(t, "Azure Virtual Machine", catalogItem.OutputResourceTypeRef.Label)
+	utils.AssertEqualsString(t, "Development", catalogItem.EntitledOrganizations[0].SubtenantLabel)
+	utils.AssertEqualsString(t, "GET: Request Template", catalogItem.Links[0].Rel)
+	utils.AssertTrue(t, "Development is entitled", catalogItem.IsEntitled("b2470b94-cbca-43db-be37-803cca7b0f1a"))
+	utils.AssertFalse(t, "other groups are not entitled", catalogItem.IsEntitled("0a5d0d1e-3d9b-4bb2-bb3e-a1dce56e4af9"))
+
+	// null properties do not break the lookups
+	c.ClearLookupCache()
+	server.reset()
+	server.register(GET, url, respond(200, nullNameEntitledCatalogItemViewsResponse))
+	catalogItems, err = c.ListEntitledCatalogItemViews(context.Background(), nil)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "", catalogItems[0].Name)
+	utils.AssertFalse(t, "no group is entitled", catalogItems[0].IsEntitled("b2470b94-cbca-43db-be37-803cca7b0f1a"))
+	catalogItemID, err := c.ReadCatalogItemByName("CentOs")
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "feaedf73-560c-4612-a573-41667e017691", catalogItemID)
+}
+
+func TestGetEntitledCatalogItemView(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	catalogItemID := "feaedf73-560c-4612-a573-41667e017691"
+	url := c.BuildEncodedURL(EntitledCatalogItemViewsAPI+"/"+catalogItemID, nil)
+	server.register(GET, url, respond(200, entitledCatalogItemViewResponse))
+
+	catalogItem, err := c.GetEntitledCatalogItemView(context.Background(), catalogItemID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "CentOs", catalogItem.Name)
+	utils.AssertEqualsString(t, "Composite Blueprint", catalogItem.CatalogItemTypeRef.Label)
+	utils.AssertEqualsString(t, "2018-09-18T20:04:55.805Z", catalogItem.DateCreated.Format("2006-01-02T15:04:05.000Z"))
+
+	catalogItem, err = c.GetEntitledCatalogItemView(context.Background(), "635e5v-8e37efd60-hdgdh")
+	utils.AssertNotNilError(t, err)
+	utils.AssertNil(t, catalogItem)
 }
 
 func TestGetBusinessGroupID(t *testing.T) {
@@ -133,6 +207,74 @@ func TestGetRequestStatus(t *testing.T) {
 	utils.AssertNil(t, requestStatus)
 }
 
+func TestGetRequest(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(ConsumerRequests+"/"+"%s", mockRequestID), nil)
+	server.register(GET, url, respond(200, requestStatusResponse))
+
+	request, err := c.GetRequest(context.Background(), mockRequestID)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, request.ID)
+	utils.AssertEqualsString(t, "feaedf73-560c-4612-a573-41667e017691", request.CatalogItemRef.ID)
+	utils.AssertEqualsString(t, "b2470b94-cbca-43db-be37-803cca7b0f1a", request.Organization.SubtenantRef)
+	utils.AssertEqualsString(t, "Prativa's deployment 1", request.Description)
+	utils.AssertTrue(t, "the request has not completed", request.RequestCompletion == nil)
+
+	server.reset()
+	server.register(GET, url, respond(400, requestStatusErrResponse))
+	_, err = c.GetRequest(context.Background(), mockRequestID)
+	utils.AssertNotNilError(t, err)
+}
+
+func TestFindDeploymentByName(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	url := c.BuildEncodedURL(ConsumerResources, nil)
+	server.register(GET, url, respond(200, resourceActionsResponse))
+
+	deployment, err := c.FindDeploymentByName(context.Background(), "Prativa_CentOs-66559687")
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "69ddca78-7ed7-45e2-9f05-a4a4c8803e5c", deployment.ID)
+	utils.AssertEqualsString(t, "6ec160e5-41c5-4b1d-8ddc-e89c426957c6", deployment.RequestID)
+
+	// the machines of the deployment are not deployments
+	_, err = c.FindDeploymentByName(context.Background(), "Development0233")
+	utils.AssertNotNilError(t, err)
+
+	// the resource by id
+	server.register(GET, c.BuildEncodedURL(ConsumerResources+"/69ddca78-7ed7-45e2-9f05-a4a4c8803e5c", nil), respond(200, entitledResourceResponse))
+	resource, err := c.GetResource(context.Background(), "69ddca78-7ed7-45e2-9f05-a4a4c8803e5c")
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, DeploymentResourceType, resource.ResourceTypeRef.ID)
+	utils.AssertEqualsString(t, "6ec160e5-41c5-4b1d-8ddc-e89c426957c6", resource.RequestID)
+}
+
+func TestCancelRequest(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	server := newFakeServer(c)
+
+	mockRequestID := "adca9535-4a35-4981-8864-28643bd990b0"
+	url := c.BuildEncodedURL(fmt.Sprintf(CancelRequestAPI, mockRequestID), nil)
+	server.register(POST, url, respond(200, ""))
+
+	err := c.CancelRequest(context.Background(), mockRequestID)
+	utils.AssertNilError(t, err)
+
+	// the request is already provisioning
+	server.reset()
+	server.register(POST, url, respond(400, requestNotCancellableResponse))
+	err = c.CancelRequest(context.Background(), mockRequestID)
+	utils.AssertNotNilError(t, err)
+	utils.AssertContainsString(t, "cannot be cancelled", err.Error())
+}
+
 func TestGetRequestResourceView(t *testing.T) {
 	httpmock.ActivateNonDefault(client.Client)
 	defer httpmock.DeactivateAndReset()
@@ -145,6 +287,7 @@ func TestGetRequestResourceView(t *testing.T) {
 	resourceView, err := client.GetRequestResourceView(mockRequestID)
 	utils.AssertNilError(t, err)
 	utils.AssertNotNil(t, resourceView)
+	utils.AssertEqualsString(t, "2019-03-04T00:11:12.040Z", resourceView.Content[0].Lease.End)
 
 	// invalid request id
 	mockRequestID = "gd78tegd-0e737egd-jhdg"
@@ -0,0 +1,266 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// wait defaults
+const (
+	DefaultWaitPollInterval  = 30 * time.Second
+	DefaultWaitMaxPollErrors = 3
+)
+
+// WaitOptions controls how WaitForRequest polls the status of a request
+type WaitOptions struct {
+	// InitialDelay is the wait before the first poll
+	InitialDelay time.Duration
+	// PollInterval is the wait between two polls
+	PollInterval time.Duration
+	// Backoff multiplies the poll interval after every poll, a Backoff of 1 or less
+	// keeps the interval constant
+	Backoff float64
+	// MaxPollInterval caps the poll interval when there is a Backoff, 0 means no limit
+	MaxPollInterval time.Duration
+	// MaxDuration is how long to wait for the request to complete, 0 means no limit
+	MaxDuration time.Duration
+	// MaxPollErrors is the number of consecutive transient errors tolerated when polling
+	MaxPollErrors int
+	// OnProgress is called with the status of the request every time its phase changes
+	OnProgress func(status *RequestStatusView)
+}
+
+// DefaultWaitOptions returns the options WaitForRequest polls with by default
+func DefaultWaitOptions() WaitOptions {
+	return WaitOptions{
+		InitialDelay:  DefaultWaitPollInterval,
+		PollInterval:  DefaultWaitPollInterval,
+		Backoff:       1,
+		MaxPollErrors: DefaultWaitMaxPollErrors,
+	}
+}
+
+// RequestResult is the state of a request WaitForRequest has waited for
+type RequestResult struct {
+	RequestID         string
+	Phase             string
+	CompletionState   string
+	CompletionDetails string
+}
+
+// Succeeded returns true if the request has completed successfully
+func (r *RequestResult) Succeeded() bool {
+	return r.Phase == Successful
+}
+
+// Err returns a *RequestFailedError if the request has not completed successfully
+func (r *RequestResult) Err() error {
+	if r.Succeeded() {
+		return nil
+	}
+	details := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(r.CompletionDetails), requestFailedPrefix))
+	return &RequestFailedError{
+		RequestID:         r.RequestID,
+		Phase:             r.Phase,
+		CompletionState:   r.CompletionState,
+		CompletionDetails: r.CompletionDetails,
+		Component:         failedComponent(details),
+		details:           details,
+	}
+}
+
+// RequestFailedError describes why a request did not complete successfully, as told by the
+// completion details of the request and the resources of its components
+type RequestFailedError struct {
+	RequestID         string
+	Phase             string
+	CompletionState   string
+	CompletionDetails string
+	// Component is the component of the blueprint which failed, the first of the Components
+	// or, when the resources of the request are unknown, the one the details start with
+	Component string
+	// Components are the components whose request did not succeed, see RequestError
+	Components []ComponentFailure
+
+	// details are the completion details without their "Request failed:" prefix
+	details string
+}
+
+// ComponentFailure is a component of a blueprint whose request, the child request of the
+// catalog request, or the last operation on its resource did not succeed. vRA 7 tells the
+// state of these requests with the resources of the catalog request, not their completion details.
+type ComponentFailure struct {
+	Component    string
+	ResourceID   string
+	Name         string
+	RequestState string
+	Status       string
+}
+
+// String describes the component and the state of its request
+func (f ComponentFailure) String() string {
+	message := fmt.Sprintf("the request of the component %s is %s", f.Component, f.RequestState)
+	if f.Name != "" {
+		message += fmt.Sprintf(", its resource %s is %s", f.Name, f.Status)
+	}
+	return message
+}
+
+// Error describes the request and the details it failed with
+func (e *RequestFailedError) Error() string {
+	message := fmt.Sprintf("The request %s is %s", e.RequestID, e.Phase)
+	if e.CompletionState != "" && e.CompletionState != e.Phase {
+		message += fmt.Sprintf(", completion state %s", e.CompletionState)
+	}
+	if e.Component != "" {
+		message += fmt.Sprintf(", the component %s failed", e.Component)
+	}
+	if e.details != "" {
+		message += ": " + e.details
+	}
+	for _, component := range e.Components {
+		message += "\n - " + component.String()
+	}
+	return message
+}
+
+// RequestError returns the error of a request which has not completed successfully, like
+// result.Err, with the components of the request which failed. The failures of the components
+// are read from the resources of the request, if they cannot be read the error only tells
+// the completion details of the request.
+func (c *APIClient) RequestError(ctx context.Context, result *RequestResult) error {
+	err := result.Err()
+	failure, ok := err.(*RequestFailedError)
+	if !ok {
+		return err
+	}
+	resourceView, viewErr := c.GetRequestResourceViewWithContext(ctx, result.RequestID)
+	if viewErr != nil {
+		log.Warning("Unable to read the resources of the request %s: %v", result.RequestID, viewErr)
+		return failure
+	}
+	for _, resource := range resourceView.Content {
+		component := resource.ResourcesData.Component
+		if component == "" || resource.RequestState == "" || resource.RequestState == Successful {
+			continue
+		}
+		failure.Components = append(failure.Components, ComponentFailure{
+			Component:    component,
+			ResourceID:   resource.ResourceID,
+			Name:         resource.Name,
+			RequestState: resource.RequestState,
+			Status:       resource.Status,
+		})
+	}
+	if len(failure.Components) > 0 {
+		failure.Component = failure.Components[0].Component
+	}
+	return failure
+}
+
+// requestFailedPrefix starts the completion details of the requests which failed
+const requestFailedPrefix = "Request failed:"
+
+// componentErrorPattern matches the error of a component at the start of the completion
+// details, like "Machine vSphereVM1: CloneVM : [CloneVM_Task] - A general system error occurred."
+var componentErrorPattern = regexp.MustCompile(`^(?:Machine|Component|Software) ([^\s:]+)\s*:`)
+
+// failedComponent returns the component the completion details start with, if any
+func failedComponent(details string) string {
+	if match := componentErrorPattern.FindStringSubmatch(details); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// WaitTimeoutError is returned by WaitForRequest when the request is still running
+// after the MaxDuration of the wait
+type WaitTimeoutError struct {
+	RequestID string
+	Phase     string
+	Duration  time.Duration
+}
+
+// Error describes the request still running
+func (e *WaitTimeoutError) Error() string {
+	if e.Phase == "" {
+		return fmt.Sprintf("The status of the request %s is still unknown after %v", e.RequestID, e.Duration)
+	}
+	return fmt.Sprintf("The request %s is still %s after %v", e.RequestID, e.Phase, e.Duration)
+}
+
+// IsTerminalPhase returns true if a request in this phase will not change anymore
+func IsTerminalPhase(phase string) bool {
+	return phase == Successful || phase == Failed || phase == Rejected
+}
+
+// WaitForRequest polls the status of the request until it has completed, successfully or not,
+// and returns its final state. If the wait is interrupted, by the cancellation of ctx, the
+// MaxDuration of the options or polling errors, the last known state of the request is
+// returned with the error.
+func (c *APIClient) WaitForRequest(ctx context.Context, requestID string, opts WaitOptions) (*RequestResult, error) {
+	waitCtx := ctx
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.MaxDuration)
+		defer cancel()
+	}
+
+	result := &RequestResult{RequestID: requestID}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultWaitPollInterval
+	}
+	wait := opts.InitialDelay
+	pollErrors := 0
+	for {
+		if err := sleep(waitCtx, wait); err != nil {
+			return result, waitError(ctx, result, opts)
+		}
+		wait = interval
+		if opts.Backoff > 1 {
+			interval = time.Duration(float64(interval) * opts.Backoff)
+			if opts.MaxPollInterval > 0 && interval > opts.MaxPollInterval {
+				interval = opts.MaxPollInterval
+			}
+		}
+
+		status, err := c.GetRequestStatusWithContext(waitCtx, requestID)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return result, waitError(ctx, result, opts)
+			}
+			pollErrors++
+			if !c.RetryPolicy.IsRetryable(err) || pollErrors > opts.MaxPollErrors {
+				return result, err
+			}
+			log.Info("Unable to read the status of the request %s, polling again: %v", requestID, err)
+			continue
+		}
+		pollErrors = 0
+
+		phaseChanged := status.Phase != result.Phase
+		result.Phase = status.Phase
+		result.CompletionState = status.RequestCompletion.RequestCompletionState
+		result.CompletionDetails = status.RequestCompletion.CompletionDetails
+		if phaseChanged && opts.OnProgress != nil {
+			opts.OnProgress(status)
+		}
+		if IsTerminalPhase(status.Phase) {
+			return result, nil
+		}
+		log.Info("The request %s is %s", requestID, status.Phase)
+	}
+}
+
+// waitError returns the error of a wait which was interrupted, because ctx was cancelled
+// or the MaxDuration of the wait is over
+func waitError(ctx context.Context, result *RequestResult, opts WaitOptions) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return &WaitTimeoutError{RequestID: result.RequestID, Phase: result.Phase, Duration: opts.MaxDuration}
+}
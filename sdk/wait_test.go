@@ -0,0 +1,203 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware/terraform-provider-vra7/utils"
+)
+
+// requestStatusSequence returns an HTTPClient which answers the status polls with the
+// given responses in turn, the last one is repeated
+func requestStatusSequence(polls *int, responses ...APIResponse) HTTPClient {
+	return HTTPClientFunc(func(req *APIRequest) (*APIResponse, error) {
+		i := *polls
+		*polls++
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		resp := responses[i]
+		return &resp, nil
+	})
+}
+
+func requestStatus(phase, state, details string) APIResponse {
+	body := fmt.Sprintf(`{"phase":"%s","requestCompletion":{"requestCompletionState":"%s","CompletionDetails":"%s"}}`,
+		phase, state, details)
+	return APIResponse{Status: "200 OK", StatusCode: 200, Body: []byte(body)}
+}
+
+func fastWaitOptions() WaitOptions {
+	opts := DefaultWaitOptions()
+	opts.InitialDelay = 0
+	opts.PollInterval = time.Millisecond
+	return opts
+}
+
+func TestWaitForRequest(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	polls := 0
+	c.HTTPClient = requestStatusSequence(&polls,
+		requestStatus("PENDING_PRE_APPROVAL", "", ""),
+		requestStatus(InProgress, "", ""),
+		requestStatus(InProgress, "", ""),
+		requestStatus(Successful, "SUCCESSFUL", "Request succeeded. Created vSphereVM1."))
+
+	var phases []string
+	opts := fastWaitOptions()
+	opts.Backoff = 2
+	opts.MaxPollInterval = 4 * time.Millisecond
+	opts.OnProgress = func(status *RequestStatusView) {
+		phases = append(phases, status.Phase)
+	}
+	result, err := c.WaitForRequest(context.Background(), "adca9535-4a35-4981-8864-28643bd990b0", opts)
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the request succeeded", result.Succeeded())
+	utils.AssertEqualsString(t, "adca9535-4a35-4981-8864-28643bd990b0", result.RequestID)
+	utils.AssertEqualsString(t, "SUCCESSFUL", result.CompletionState)
+	utils.AssertEqualsString(t, "Request succeeded. Created vSphereVM1.", result.CompletionDetails)
+	utils.AssertEqualsInt(t, 4, polls)
+	// the progress is only reported when the phase changes
+	utils.AssertEqualsInt(t, 3, len(phases))
+	utils.AssertEqualsString(t, InProgress, phases[1])
+
+	// a failed request is a result, not an error
+	polls = 0
+	c.HTTPClient = requestStatusSequence(&polls, requestStatus(Failed, "FAILED", "Request failed: Machine vSphereVM1: CloneVM : [CloneVM_Task] - A general system error occurred."))
+	result, err = c.WaitForRequest(context.Background(), "adca9535-4a35-4981-8864-28643bd990b0", fastWaitOptions())
+	utils.AssertNilError(t, err)
+	utils.AssertFalse(t, "the request failed", result.Succeeded())
+	utils.AssertEqualsString(t, Failed, result.Phase)
+	utils.AssertContainsString(t, "CloneVM_Task", result.CompletionDetails)
+}
+
+func TestWaitForRequestPollErrors(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	c.RetryPolicy.MaxRetries = 0
+	unavailable := APIResponse{Status: "503 Service Unavailable", StatusCode: 503, Body: []byte("Service Unavailable")}
+
+	// transient errors are tolerated
+	polls := 0
+	c.HTTPClient = requestStatusSequence(&polls, unavailable, unavailable, requestStatus(Successful, "SUCCESSFUL", ""))
+	result, err := c.WaitForRequest(context.Background(), "adca9535-4a35-4981-8864-28643bd990b0", fastWaitOptions())
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the request succeeded", result.Succeeded())
+	utils.AssertEqualsInt(t, 3, polls)
+
+	// up to MaxPollErrors in a row
+	polls = 0
+	c.HTTPClient = requestStatusSequence(&polls, unavailable)
+	_, err = c.WaitForRequest(context.Background(), "adca9535-4a35-4981-8864-28643bd990b0", fastWaitOptions())
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsInt(t, DefaultWaitMaxPollErrors+1, polls)
+
+	// other errors end the wait
+	polls = 0
+	notFound := APIResponse{Status: "404 Not Found", StatusCode: 404, Body: []byte(requestStatusErrResponse)}
+	c.HTTPClient = requestStatusSequence(&polls, notFound)
+	_, err = c.WaitForRequest(context.Background(), "adca9535-4a35-4981-8864-28643bd990b0", fastWaitOptions())
+	utils.AssertTrue(t, "the request is not found", IsNotFound(err))
+	utils.AssertEqualsInt(t, 1, polls)
+}
+
+func TestWaitForRequestTimeout(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	polls := 0
+	c.HTTPClient = requestStatusSequence(&polls, requestStatus(InProgress, "", ""))
+
+	opts := fastWaitOptions()
+	opts.MaxDuration = 20 * time.Millisecond
+	result, err := c.WaitForRequest(context.Background(), "adca9535-4a35-4981-8864-28643bd990b0", opts)
+	timeoutErr, ok := err.(*WaitTimeoutError)
+	utils.AssertTrue(t, "the wait timed out", ok)
+	utils.AssertEqualsString(t, InProgress, timeoutErr.Phase)
+	utils.AssertEqualsString(t, InProgress, result.Phase)
+
+	// the cancellation of the context is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.WaitForRequest(ctx, "adca9535-4a35-4981-8864-28643bd990b0", opts)
+	utils.AssertEqualsString(t, context.Canceled.Error(), err.Error())
+}
+
+func TestRequestFailedError(t *testing.T) {
+	result := &RequestResult{RequestID: "adca9535-4a35-4981-8864-28643bd990b0", Phase: Successful}
+	utils.AssertNilError(t, result.Err())
+
+	result = &RequestResult{
+		RequestID:         "adca9535-4a35-4981-8864-28643bd990b0",
+		Phase:             Failed,
+		CompletionState:   "PROVIDER_FAILED",
+		CompletionDetails: "Request failed: Machine vSphereVM1: CloneVM : [CloneVM_Task] - Invalid configuration; the datastore is full.",
+	}
+	err := result.Err()
+	failure, ok := err.(*RequestFailedError)
+	utils.AssertTrue(t, "the request failed", ok)
+	utils.AssertEqualsString(t, "vSphereVM1", failure.Component)
+	utils.AssertEqualsString(t, "The request adca9535-4a35-4981-8864-28643bd990b0 is FAILED, completion state PROVIDER_FAILED, "+
+		"the component vSphereVM1 failed: Machine vSphereVM1: CloneVM : [CloneVM_Task] - Invalid configuration; the datastore is full.",
+		err.Error())
+
+	// details which do not name a component
+	result = &RequestResult{
+		RequestID:         "adca9535-4a35-4981-8864-28643bd990b0",
+		Phase:             Rejected,
+		CompletionState:   Rejected,
+		CompletionDetails: "The request was rejected by the approver.",
+	}
+	utils.AssertEqualsString(t, "The request adca9535-4a35-4981-8864-28643bd990b0 is REJECTED: The request was rejected by the approver.",
+		result.Err().Error())
+}
+
+func TestRequestError(t *testing.T) {
+	c := newMockClient()
+	c.BearerToken = "Bearer mock-token"
+	result := &RequestResult{
+		RequestID:         "adca9535-4a35-4981-8864-28643bd990b0",
+		Phase:             Failed,
+		CompletionState:   "PROVIDER_FAILED",
+		CompletionDetails: "Request failed: The deployment could not be provisioned.",
+	}
+
+	// the components whose request failed are read from the resources of the request
+	var requests []*APIRequest
+	c.HTTPClient = stubResponse(&requests, 200, `{"content":[
+		{"resourceId":"1ffcd9fe-d96e-47ce-9509-cdbae24862e3","name":"Prativa_CentOs-86390713",
+		 "resourceType":"composition.resource.type.deployment","requestState":"FAILED","data":{}},
+		{"resourceId":"4a7a33a8-6b21-461c-a618-442044059ef8","name":"Development0231","status":"Missing",
+		 "resourceType":"Infrastructure.Virtual","requestState":"FAILED","data":{"Component":"vSphereVM1"}},
+		{"resourceId":"ed2a4ed2-e7a4-4bd5-a8ca-b0d7f5e2a8c8","name":"Development0232","status":"On",
+		 "resourceType":"Infrastructure.Virtual","requestState":"SUCCESSFUL","data":{"Component":"vSphereVM2"}}],
+		"metadata":{"size":20,"totalElements":3,"totalPages":1,"number":1,"offset":0}}`)
+	err := c.RequestError(context.Background(), result)
+	failure, ok := err.(*RequestFailedError)
+	utils.AssertTrue(t, "the request failed", ok)
+	utils.AssertTrue(t, "the resources of the request are read",
+		strings.HasPrefix(requests[0].URL, fmt.Sprintf(GetRequestResourceViewAPI, result.RequestID)))
+	utils.AssertEqualsInt(t, 1, len(failure.Components))
+	utils.AssertEqualsString(t, "vSphereVM1", failure.Component)
+	utils.AssertEqualsString(t, "4a7a33a8-6b21-461c-a618-442044059ef8", failure.Components[0].ResourceID)
+	utils.AssertEqualsString(t, "The request adca9535-4a35-4981-8864-28643bd990b0 is FAILED, completion state PROVIDER_FAILED, "+
+		"the component vSphereVM1 failed: The deployment could not be provisioned.\n"+
+		" - the request of the component vSphereVM1 is FAILED, its resource Development0231 is Missing",
+		err.Error())
+
+	// the completion details are still reported when the resources cannot be read
+	requests = nil
+	c.HTTPClient = stubResponse(&requests, 404, requestStatusErrResponse)
+	err = c.RequestError(context.Background(), result)
+	utils.AssertEqualsString(t, "The request adca9535-4a35-4981-8864-28643bd990b0 is FAILED, completion state PROVIDER_FAILED: "+
+		"The deployment could not be provisioned.", err.Error())
+
+	// and a request which succeeded is not an error
+	requests = nil
+	result = &RequestResult{RequestID: "adca9535-4a35-4981-8864-28643bd990b0", Phase: Successful}
+	utils.AssertNilError(t, c.RequestError(context.Background(), result))
+	utils.AssertEqualsInt(t, 0, len(requests))
+}
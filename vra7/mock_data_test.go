@@ -157,4 +157,147 @@ var (
 		   }
 		}
 	 }`
+
+	requestNotFoundResponse = `{
+		"errors":[
+		   {
+			  "code":20111,
+			  "source":null,
+			  "message":"Unable to find the specified request in the service catalog: 594bf7ec-c8d2-4a0d-8477-553ed987aa48.",
+			  "systemMessage":"Unable to find the specified request in the service catalog: 594bf7ec-c8d2-4a0d-8477-553ed987aa48.",
+			  "moreInfoUrl":null
+		   }
+		]
+	 }`
+
+	systemExceptionResponse = `{
+		"errors":[
+		   {
+			  "code":50505,
+			  "source":null,
+			  "message":"System exception.",
+			  "systemMessage":null,
+			  "moreInfoUrl":null
+		   }
+		]
+	 }`
+
+	requestNotCancellableResponse = `{
+		"errors":[
+		   {
+			  "code":20140,
+			  "source":null,
+			  "message":"The request cannot be cancelled in its current state.",
+			  "systemMessage":"The request cannot be cancelled in its current state.",
+			  "moreInfoUrl":null
+		   }
+		]
+	 }`
+
+	aboutResponse = `{
+		"releaseVersion":"7.5.0 (build 10053500)",
+		"buildNumber":"10053500",
+		"buildDate":"2018-09-11T23:23:01.000Z",
+		"productBuildNumber":"10053500"
+	 }`
+
+	catalogRequestResponse = `{
+		"@type":"CatalogItemRequest",
+		"id":"594bf7ec-c8d2-4a0d-8477-553ed987aa48",
+		"state":"SUCCESSFUL",
+		"description":"Prativa's deployment 1",
+		"reasons":"Testing the vRA 7 Terraform plugin",
+		"organization":{
+		   "tenantRef":"qe",
+		   "tenantLabel":"QETenant",
+		   "subtenantRef":"b2470b94-cbca-43db-be37-803cca7b0f1a",
+		   "subtenantLabel":"Development"
+		},
+		"requestCompletion":{
+		   "requestCompletionState":"SUCCESSFUL",
+		   "completionDetails":"Request succeeded. Created Development0231."
+		},
+		"phase":"SUCCESSFUL",
+		"catalogItemRef":{
+		   "id":"feaedf73-560c-4612-a573-41667e017691",
+		   "label":"Prativa_CentOs"
+		}
+	 }`
+
+	requestResourceViewResponse = `{
+		"links":[],
+		"content":[
+		   {
+			  "@type":"CatalogResourceView",
+			  "resourceId":"1ffcd9fe-d96e-47ce-9509-cdbae24862e3",
+			  "name":"Prativa_CentOs-86390713",
+			  "requestId":"594bf7ec-c8d2-4a0d-8477-553ed987aa48",
+			  "requestState":"SUCCESSFUL",
+			  "resourceType":"composition.resource.type.deployment",
+			  "businessGroupId":"b2470b94-cbca-43db-be37-803cca7b0f1a",
+			  "data":{
+				 "isResumable":false
+			  }
+		   },
+		   {
+			  "@type":"CatalogResourceView",
+			  "resourceId":"4a7a33a8-6b21-461c-a618-442044059ef8",
+			  "name":"Development0231",
+			  "status":"On",
+			  "requestId":"594bf7ec-c8d2-4a0d-8477-553ed987aa48",
+			  "requestState":"SUCCESSFUL",
+			  "resourceType":"Infrastructure.Virtual",
+			  "businessGroupId":"b2470b94-cbca-43db-be37-803cca7b0f1a",
+			  "lease":{
+				 "start":"2019-02-27T00:11:12.040Z",
+				 "end":"2019-03-04T00:11:12.040Z"
+			  },
+			  "data":{
+				 "Component":"vSphereVM1",
+				 "MachineGuestOperatingSystem":"CentOS 4/5/6/7 (64-bit)",
+				 "MachineCPU":2,
+				 "MachineMemory":2048,
+				 "MachineStorage":16,
+				 "MachineName":"Development0231",
+				 "ip_address":"10.112.4.231"
+			  }
+		   }
+		]
+	 }`
+
+	deploymentResourcesResponse = `{
+		"links":[],
+		"content":[
+		   {
+			  "@type":"CatalogResource",
+			  "id":"1ffcd9fe-d96e-47ce-9509-cdbae24862e3",
+			  "resourceTypeRef":{
+				 "id":"composition.resource.type.deployment",
+				 "label":"Deployment"
+			  },
+			  "name":"Prativa_CentOs-86390713",
+			  "status":"ACTIVE",
+			  "requestId":"594bf7ec-c8d2-4a0d-8477-553ed987aa48",
+			  "requestState":"SUCCESSFUL"
+		   }
+		],
+		"metadata":{
+		   "size":20,
+		   "totalElements":1,
+		   "totalPages":1,
+		   "number":1,
+		   "offset":0
+		}
+	 }`
+
+	failedRequestStatusResponse = `{
+		"@type":"CatalogItemRequest",
+		"id":"594bf7ec-c8d2-4a0d-8477-553ed987aa48",
+		"state":"PROVIDER_FAILED",
+		"requestCompletion":{
+		   "requestCompletionState":"FAILED",
+		   "CompletionDetails":"Request failed: Machine vSphereVM1: CloneVM : [CloneVM_Task] - A general system error occurred."
+		},
+		"phase":"FAILED"
+	 }`
 )
@@ -1,39 +1,110 @@
 package vra7
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/vmware/terraform-provider-vra7/sdk"
 )
 
+// LogoutTimeout bounds the time Logout waits for the vRA server. When terraform is done
+// with the plugin it closes the connection and kills the process 250ms later.
+const LogoutTimeout = 200 * time.Millisecond
+
+// clients are the clients configured by the provider, their sessions are closed by Logout
+var (
+	clients     []*sdk.APIClient
+	clientsLock sync.Mutex
+)
+
+// Logout closes the vRA sessions of the clients the provider has configured. It is
+// called when the plugin process ends, which leaves little time: the tokens are revoked
+// concurrently, on a best effort basis, and the ones not revoked in time expire later.
+func Logout() {
+	ctx, cancel := context.WithTimeout(context.Background(), LogoutTimeout)
+	defer cancel()
+
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+	var wg sync.WaitGroup
+	for _, vraClient := range clients {
+		wg.Add(1)
+		go func(vraClient *sdk.APIClient) {
+			defer wg.Done()
+			if err := vraClient.Logout(ctx); err != nil {
+				log.Warning("Unable to revoke the vRA session token: %v", err)
+			}
+		}(vraClient)
+	}
+	wg.Wait()
+	clients = nil
+}
+
 //Provider - This function initializes the provider schema
 //also the config function and resource mapping
 func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
-		Schema:        providerSchema(),
-		ConfigureFunc: providerConfig,
+	provider := &schema.Provider{
+		Schema: providerSchema(),
 		ResourcesMap: map[string]*schema.Resource{
 			"vra7_deployment": resourceVra7Deployment(),
 		},
 	}
+	provider.ConfigureFunc = func(r *schema.ResourceData) (interface{}, error) {
+		return providerConfig(provider.StopContext(), r)
+	}
+	return provider
+}
+
+// providerMeta is handed by the provider to its resources
+type providerMeta struct {
+	client *sdk.APIClient
+	// stopContext is cancelled when terraform asks the provider to stop, for example
+	// when the user interrupts a run, so that pending API calls and waits are aborted
+	stopContext context.Context
+}
+
+// stopContext returns the context of the provider, cancelled when terraform asks it to stop
+func stopContext(meta interface{}) context.Context {
+	return meta.(*providerMeta).stopContext
 }
 
 //providerSchema - To set provider fields
 func providerSchema() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"username": {
-			Type:        schema.TypeString,
-			Required:    true,
-			DefaultFunc: schema.EnvDefaultFunc("VRA7_USERNAME", nil),
-			Description: "Tenant administrator username.",
+			Type:          schema.TypeString,
+			Optional:      true,
+			DefaultFunc:   schema.EnvDefaultFunc("VRA7_USERNAME", nil),
+			ConflictsWith: []string{"token", "token_file"},
+			Description:   "Tenant administrator username.",
 		},
 		"password": {
-			Type:        schema.TypeString,
-			Required:    true,
-			DefaultFunc: schema.EnvDefaultFunc("VRA7_PASSWORD", nil),
-			Description: "Tenant administrator password.",
+			Type:          schema.TypeString,
+			Optional:      true,
+			DefaultFunc:   schema.EnvDefaultFunc("VRA7_PASSWORD", nil),
+			ConflictsWith: []string{"token", "token_file"},
+			Description:   "Tenant administrator password.",
+		},
+		"token": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			Sensitive:     true,
+			DefaultFunc:   schema.EnvDefaultFunc("VRA7_TOKEN", nil),
+			ConflictsWith: []string{"username", "password", "token_file"},
+			Description:   "Bearer token issued beforehand, used instead of the username and password.",
+		},
+		"token_file": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			DefaultFunc:   schema.EnvDefaultFunc("VRA7_TOKEN_FILE", nil),
+			ConflictsWith: []string{"username", "password", "token"},
+			Description:   "Path to a file holding a bearer token issued beforehand, used instead of the username and password.",
 		},
 		"tenant": {
 			Type:        schema.TypeString,
@@ -55,11 +126,114 @@ func providerSchema() map[string]*schema.Schema {
 			Optional:    true,
 			Description: "Specify whether to validate TLS certificates.",
 		},
+		"ca_file": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			DefaultFunc:   schema.EnvDefaultFunc("VRA7_CA_FILE", nil),
+			ConflictsWith: []string{"ca_cert"},
+			Description:   "Path to a PEM bundle of the certificate authorities to trust in addition to the system ones.",
+		},
+		"ca_cert": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"ca_file"},
+			Description:   "PEM encoded certificate authorities to trust in addition to the system ones.",
+		},
+		"client_cert_file": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_CLIENT_CERT_FILE", nil),
+			Description: "Path to the PEM encoded certificate to authenticate to the vRA server with.",
+		},
+		"client_key_file": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_CLIENT_KEY_FILE", nil),
+			Description: "Path to the PEM encoded private key of the client certificate.",
+		},
+		"tls_server_name": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Host name to verify the certificate of the vRA server against, instead of the one in host.",
+		},
+		"proxy_url": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_PROXY_URL", nil),
+			Description: "URL of the proxy to connect to the vRA server through. If not set, the HTTPS_PROXY and NO_PROXY environment variables are used.",
+		},
+		"proxy_username": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_PROXY_USERNAME", nil),
+			Description: "Username to authenticate to the proxy with.",
+		},
+		"proxy_password": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_PROXY_PASSWORD", nil),
+			Description: "Password to authenticate to the proxy with.",
+		},
+		"no_proxy": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Description: "Hosts, domains, IP addresses or CIDR ranges to connect to directly, without the proxy.",
+		},
+		"trace": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_TRACE", false),
+			Description: "Log the requests to and responses from the vRA server at debug level, with their secrets redacted.",
+		},
+		"max_retries": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_MAX_RETRIES", sdk.DefaultMaxRetries),
+			Description: "Maximum number of times a request failing with a transient error is retried, 0 disables retries.",
+		},
+		"retry_wait_min": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     int(sdk.DefaultMinBackoff / time.Second),
+			Description: "Wait in seconds before the first retry, doubled on every following retry.",
+		},
+		"retry_wait_max": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     int(sdk.DefaultMaxBackoff / time.Second),
+			Description: "Maximum wait in seconds between two retries.",
+		},
+		"max_idle_conns_per_host": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     sdk.DefaultMaxIdleConnsPerHost,
+			Description: "Maximum number of idle connections kept open to the vRA server.",
+		},
+		"max_conns_per_host": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     0,
+			Description: "Maximum number of connections opened to the vRA server at the same time, 0 means no limit.",
+		},
+		"idle_conn_timeout": {
+			Type:        schema.TypeInt,
+			Optional:    true,
+			Default:     int(sdk.DefaultIdleConnTimeout / time.Second),
+			Description: "Time in seconds an idle connection is kept open, 0 means no limit.",
+		},
+		"disable_keep_alives": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("VRA7_DISABLE_KEEP_ALIVES", false),
+			Description: "Close the connection after every request, for load balancers which do not handle persistent connections well.",
+		},
 	}
 }
 
 //Function use - To authenticate terraform provider
-func providerConfig(r *schema.ResourceData) (interface{}, error) {
+func providerConfig(ctx context.Context, r *schema.ResourceData) (interface{}, error) {
 	//Create a client handle to perform REST calls for various operations upon the resource
 
 	user := r.Get("username").(string)
@@ -68,15 +242,140 @@ func providerConfig(r *schema.ResourceData) (interface{}, error) {
 	baseURL := r.Get("host").(string)
 	insecure := r.Get("insecure").(bool)
 	vraClient := sdk.NewClient(user, password, tenant, baseURL, insecure)
+	vraClient.RetryPolicy.MaxRetries = r.Get("max_retries").(int)
+	vraClient.RetryPolicy.MinBackoff = time.Duration(r.Get("retry_wait_min").(int)) * time.Second
+	vraClient.RetryPolicy.MaxBackoff = time.Duration(r.Get("retry_wait_max").(int)) * time.Second
+	vraClient.Trace = r.Get("trace").(bool)
+
+	tlsConfig, err := readTLSConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	err = vraClient.ConfigureTLS(*tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error: Invalid TLS configuration: %v", err)
+	}
+
+	err = vraClient.ConfigureProxy(readProxyConfig(r))
+	if err != nil {
+		return nil, fmt.Errorf("Error: Invalid proxy configuration: %v", err)
+	}
+
+	err = vraClient.ConfigureConnections(sdk.ConnectionConfig{
+		MaxIdleConnsPerHost: r.Get("max_idle_conns_per_host").(int),
+		MaxConnsPerHost:     r.Get("max_conns_per_host").(int),
+		IdleConnTimeout:     time.Duration(r.Get("idle_conn_timeout").(int)) * time.Second,
+		DisableKeepAlives:   r.Get("disable_keep_alives").(bool),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error: Invalid connection configuration: %v", err)
+	}
+
+	token, err := readToken(r)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		if user != "" || password != "" {
+			return nil, fmt.Errorf("Error: The username and password cannot be set with a token")
+		}
+		err = vraClient.UseToken(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("Error: Invalid token: %v", err)
+		}
+		detectVersion(ctx, vraClient)
+		return &providerMeta{client: vraClient, stopContext: ctx}, nil
+	}
+	if user == "" || password == "" {
+		return nil, fmt.Errorf("Error: Either the username and password or a token must be set")
+	}
 
 	//Authenticate user
-	err := vraClient.Authenticate()
+	err = vraClient.AuthenticateWithContext(ctx)
 
 	//Raise an error on authentication fail
 	if err != nil {
 		return nil, fmt.Errorf("Error: Unable to get auth token: %v", err)
 	}
 
+	clientsLock.Lock()
+	clients = append(clients, vraClient)
+	clientsLock.Unlock()
+
+	detectVersion(ctx, vraClient)
+
 	//Return client handle on success
-	return &vraClient, nil
+	return &providerMeta{client: vraClient, stopContext: ctx}, nil
+}
+
+// detectVersion reads the version of the vRA server. A server which cannot tell its
+// version is used as if it supported every feature, and a version the provider does not
+// support is only warned about.
+func detectVersion(ctx context.Context, vraClient *sdk.APIClient) {
+	version, err := vraClient.DetectVersion(ctx)
+	if err == nil {
+		return
+	}
+	if version.IsZero() {
+		log.Warning("Unable to detect the version of the vRA server: %v", err)
+		return
+	}
+	log.Warning("Unsupported vRA server: %v", err)
+}
+
+// readToken returns the bearer token of the provider configuration, read from the
+// token_file if it is set
+func readToken(r *schema.ResourceData) (string, error) {
+	path := r.Get("token_file").(string)
+	if path == "" {
+		return strings.TrimSpace(r.Get("token").(string)), nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Error: Unable to read the token_file %s: %v", path, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// readTLSConfig reads the certificates referred to in the provider configuration
+func readTLSConfig(r *schema.ResourceData) (*sdk.TLSConfig, error) {
+	tlsConfig := sdk.TLSConfig{
+		Insecure:       r.Get("insecure").(bool),
+		CACertificates: []byte(r.Get("ca_cert").(string)),
+		ServerName:     r.Get("tls_server_name").(string),
+	}
+
+	files := []struct {
+		key  string
+		dest *[]byte
+	}{
+		{"ca_file", &tlsConfig.CACertificates},
+		{"client_cert_file", &tlsConfig.ClientCertificate},
+		{"client_key_file", &tlsConfig.ClientKey},
+	}
+	for _, file := range files {
+		path := r.Get(file.key).(string)
+		if path == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error: Unable to read the %s %s: %v", file.key, path, err)
+		}
+		*file.dest = content
+	}
+	return &tlsConfig, nil
+}
+
+// readProxyConfig reads the proxy settings of the provider configuration
+func readProxyConfig(r *schema.ResourceData) sdk.ProxyConfig {
+	proxyConfig := sdk.ProxyConfig{
+		URL:      r.Get("proxy_url").(string),
+		Username: r.Get("proxy_username").(string),
+		Password: r.Get("proxy_password").(string),
+	}
+	for _, host := range r.Get("no_proxy").([]interface{}) {
+		proxyConfig.NoProxy = append(proxyConfig.NoProxy, host.(string))
+	}
+	return proxyConfig
 }
@@ -1,19 +1,21 @@
 package vra7
 
 import (
+	"context"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/vmware/terraform-provider-vra7/sdk"
 	"github.com/vmware/terraform-provider-vra7/utils"
 	"gopkg.in/jarcoal/httpmock.v1"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"testing"
 )
 
 var (
-	client           sdk.APIClient
+	client           *sdk.APIClient
 	mockUser         = os.Getenv("VRA7_USERNAME")
 	mockPassword     = os.Getenv("VRA7_PASSWORD")
 	mockTenant       = os.Getenv("VRA7_TENANT")
@@ -79,3 +81,73 @@ func testAccPreCheck(t *testing.T) {
 		t.Fatal("VRA7_INSECURE must be set for acceptance tests")
 	}
 }
+
+func TestReadToken(t *testing.T) {
+	r := schema.TestResourceDataRaw(t, providerSchema(), map[string]interface{}{
+		"token": " MTU1MTEyMzE1NTc5OD\n",
+	})
+	token, err := readToken(r)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "MTU1MTEyMzE1NTc5OD", token)
+
+	tokenFile, err := ioutil.TempFile("", "vra7-token")
+	utils.AssertNilError(t, err)
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("MTU1MTEyMzE1NTc5OD\n")
+	tokenFile.Close()
+
+	r = schema.TestResourceDataRaw(t, providerSchema(), map[string]interface{}{
+		"token_file": tokenFile.Name(),
+	})
+	token, err = readToken(r)
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "MTU1MTEyMzE1NTc5OD", token)
+
+	r = schema.TestResourceDataRaw(t, providerSchema(), map[string]interface{}{
+		"token_file": tokenFile.Name() + ".missing",
+	})
+	_, err = readToken(r)
+	utils.AssertNotNilError(t, err)
+}
+
+func TestLogout(t *testing.T) {
+	tokenURL := fmt.Sprintf("%s/identity/api/tokens", mockBaseURL)
+	// the id of the token in validAuthResponse
+	deleteURL := tokenURL + "/MTU1MTEyMzE1NTc5ODpiYTZkYjdhNjZlNGNkYjZmZTBiMjp0ZW5hbnQ6cWV1c2VybmFtZTpmcml0ekBjb2tlLnNxYS1ob3Jpem9uLmxvY2FsZXhwaXJhdGlvbjoxNTUxMTUxOTU1MDAwOmMyNGVjNTFiNzE1OTJhZDZjNTljMTUwMDkxMjcyNzUyZDkzNzQ0ODRkMTVlZGFhNWM0MDhjYmQ3YTM2MTljZGNiNjM3MjM1NmY1MzZlYTk1YzUyMGZiZDVjMTkzMzg3YjQzZmMwNmNlMGI5YjJkZmIwNzhlZGU2NzdiNTk3MWFk"
+	fake := stubVRA(map[string]*sdk.APIResponse{
+		"POST " + tokenURL:    stringResponse(200, validAuthResponse),
+		"DELETE " + deleteURL: stringResponse(204, ""),
+	})
+	c := mockClient(fake)
+	c.BearerToken = ""
+
+	err := c.Authenticate()
+	utils.AssertNilError(t, err)
+	clients = append(clients, c)
+
+	Logout()
+	utils.AssertEqualsInt(t, 1, fake.callCount("DELETE "+deleteURL))
+	utils.AssertEqualsString(t, "", c.BearerToken)
+	utils.AssertEqualsInt(t, 0, len(clients))
+}
+
+func TestDetectVersion(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	url := "GET " + c.BuildEncodedURL(sdk.AboutAPI, nil)
+	fake.register(url, stringResponse(200, aboutResponse))
+	detectVersion(context.Background(), c)
+	utils.AssertEqualsString(t, "7.5.0", c.Version.String())
+
+	// a server which cannot tell its version is still usable
+	c.Version = sdk.Version{}
+	fake.register(url, stringResponse(404, requestNotFoundResponse))
+	detectVersion(context.Background(), c)
+	utils.AssertTrue(t, "the version is unknown", c.Version.IsZero())
+
+	// and so is a version the provider does not support
+	fake.register(url, stringResponse(200, `{"releaseVersion":"8.0.0"}`))
+	detectVersion(context.Background(), c)
+	utils.AssertEqualsString(t, "8.0.0", c.Version.String())
+}
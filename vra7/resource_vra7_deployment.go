@@ -1,13 +1,15 @@
 package vra7
 
 import (
+	"context"
 	"fmt"
-	"reflect"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 	logging "github.com/op/go-logging"
 	"github.com/vmware/terraform-provider-vra7/sdk"
 	"github.com/vmware/terraform-provider-vra7/utils"
@@ -19,13 +21,24 @@ const (
 	DestroyActionTemplateError        = "Error retrieving destroy action template for the deployment %v: %v "
 	BusinessGroupIDNameNotMatchingErr = "The business group name %s and id %s does not belong to the same business group, provide either name or id"
 	CatalogItemIDNameNotMatchingErr   = "The catalog item name %s and id %s does not belong to the same catalog item, provide either name or id"
+	WaitInterruptedError              = "Interrupted while waiting for the request %s to complete. \nRun terraform refresh to get the latest state of your request"
+	WaitTimeoutError                  = "Request has timed out. Please try again later. \nRun terraform refresh to get the latest state of your request"
+	RequestCancelledError             = "The request %s did not complete and has been cancelled: %v"
+	RequestNotCancelledError          = "The request %s did not complete and could not be cancelled: %v. \nThe request is kept in the state, the next terraform plan or apply waits for it to complete"
+	RequestPendingError               = "The request %s did not complete, it is kept in the state and the next terraform plan or apply waits for it to complete: %v"
 )
 
-var (
-	log       = logging.MustGetLogger(utils.LoggerID)
-	vraClient *sdk.APIClient
+// timeouts of the deployment operations
+const (
+	// DefaultRequestTimeout is how long to wait for a request to complete by default
+	DefaultRequestTimeout = 15 * time.Minute
+	// CancelTimeout is how long to wait for vRA to cancel a request which did not complete,
+	// from the cancellation of the request until the request has stopped
+	CancelTimeout = time.Minute
 )
 
+var log = logging.MustGetLogger(utils.LoggerID)
+
 // ProviderSchema represents the information provided in the tf file
 type ProviderSchema struct {
 	CatalogItemName         string
@@ -47,6 +60,16 @@ func resourceVra7Deployment() *schema.Resource {
 		Read:   resourceVra7DeploymentRead,
 		Update: resourceVra7DeploymentUpdate,
 		Delete: resourceVra7DeploymentDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceVra7DeploymentImport,
+		},
+		// the timeouts are 0 unless the timeouts block sets them, see requestTimeout
+		Timeouts: &schema.ResourceTimeout{
+			Create:  schema.DefaultTimeout(0),
+			Update:  schema.DefaultTimeout(0),
+			Delete:  schema.DefaultTimeout(0),
+			Default: schema.DefaultTimeout(0),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"catalog_item_name": {
@@ -78,10 +101,25 @@ func resourceVra7Deployment() *schema.Resource {
 				Computed: true,
 				Optional: true,
 			},
+			"tenant": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
 			"wait_timeout": {
-				Type:     schema.TypeInt,
+				Type:       schema.TypeInt,
+				Optional:   true,
+				Default:    int(DefaultRequestTimeout / time.Minute),
+				Deprecated: "Use the timeouts block instead",
+			},
+			"poll_interval": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"cancel_on_timeout": {
+				Type:     schema.TypeBool,
 				Optional: true,
-				Default:  15,
 			},
 			"request_status": {
 				Type:     schema.TypeString,
@@ -94,6 +132,67 @@ func resourceVra7Deployment() *schema.Resource {
 				ForceNew: true,
 				Optional: true,
 			},
+			"resources": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"component_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"resource_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"resource_type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"machine_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ip_addresses": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"cpu": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"memory": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"storage": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"guest_os": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"lease_start": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"lease_end": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
 			"deployment_configuration": {
 				Type:     schema.TypeMap,
 				Optional: true,
@@ -134,11 +233,14 @@ func (s byLength) Swap(i, j int) {
 // This function creates a new vRA 7 Deployment using configuration in a user's Terraform file.
 // The Deployment is produced by invoking a catalog item that is specified in the configuration.
 func resourceVra7DeploymentCreate(d *schema.ResourceData, meta interface{}) error {
-	vraClient = meta.(*sdk.APIClient)
+	vraClient, err := deploymentClient(d, meta)
+	if err != nil {
+		return err
+	}
 	// Get client handle
 	p := readProviderConfiguration(d)
 
-	requestTemplate, validityErr := p.checkConfigValuesValidity(d)
+	requestTemplate, validityErr := p.checkConfigValuesValidity(stopContext(meta), vraClient, d)
 	if validityErr != nil {
 		return validityErr
 	}
@@ -154,13 +256,12 @@ func resourceVra7DeploymentCreate(d *schema.ResourceData, meta interface{}) erro
 		requestTemplate.Data[field1] = p.DeploymentConfiguration[field1]
 	}
 
-	// Get all component names in the blueprint corresponding to the catalog item.
-	var componentNameList []string
-	for field := range requestTemplate.Data {
-		if reflect.ValueOf(requestTemplate.Data[field]).Kind() == reflect.Map {
-			componentNameList = append(componentNameList, field)
-		}
+	// Get all components in the blueprint corresponding to the catalog item.
+	components, err := requestTemplate.Components()
+	if err != nil {
+		return err
 	}
+	componentNameList := requestTemplate.ComponentNames()
 	log.Info("createResource->key_list %v\n", componentNameList)
 
 	// Arrange component names in descending order of text length.
@@ -182,54 +283,73 @@ func resourceVra7DeploymentCreate(d *schema.ResourceData, meta interface{}) erro
 						"resource_configuration key is not in correct format. Expected %s to start with %s",
 						configKey, componentName+".")
 				}
-				// Function call which changes request template field values with user-supplied values
-				requestTemplate.Data[componentName] = updateRequestTemplate(
-					requestTemplate.Data[componentName].(map[string]interface{}),
-					propertyName,
-					configValue)
+				// Change the property of the component with the user-supplied value
+				err = components[componentName].SetProperty(propertyName, configValue)
+				if err != nil {
+					return fmt.Errorf("Invalid resource_configuration key %s: %v", configKey, err)
+				}
 				break
 			}
 		}
 	}
+	for componentName, component := range components {
+		err = requestTemplate.SetComponent(componentName, component)
+		if err != nil {
+			return err
+		}
+	}
 
 	log.Info("Updated template - %v\n", requestTemplate.Data)
 
 	//Fire off a catalog item request to create a deployment.
-	catalogRequest, err := vraClient.RequestCatalogItem(requestTemplate)
+	catalogRequest, err := vraClient.RequestCatalogItemWithContext(stopContext(meta), requestTemplate)
 
 	if err != nil {
 		log.Errorf("Resource Machine Request Failed: %v", err)
 		return fmt.Errorf("Resource Machine Request Failed: %v", err)
 	}
 	d.SetId(catalogRequest.ID)
-	_, err = waitForRequestCompletion(d, meta, catalogRequest.ID)
+	_, err = waitForRequestCompletion(d, meta, catalogRequest.ID, schema.TimeoutCreate)
 	if err != nil {
+		if _, pending := err.(*requestPendingError); pending {
+			// the request is kept in the state with a phase which is not terminal, so that
+			// the next refresh resumes the wait instead of requesting the deployment again.
+			// The creation does not fail, terraform would taint the deployment otherwise.
+			if d.Get("request_status").(string) == "" {
+				d.Set("request_status", sdk.Submitted)
+			}
+			if d.Get("cancel_on_timeout").(bool) {
+				cancelErr := cancelPendingRequest(vraClient, d, catalogRequest.ID, err)
+				if d.Id() == "" {
+					return cancelErr
+				}
+				if d.Get("request_status").(string) == sdk.Successful {
+					// the request has completed before vRA could cancel it
+					return resourceVra7DeploymentRead(d, meta)
+				}
+			}
+			log.Warning(RequestPendingError, catalogRequest.ID, err)
+			return nil
+		}
 		return err
 	}
 	return resourceVra7DeploymentRead(d, meta)
 }
 
-func updateRequestTemplate(templateInterface map[string]interface{}, field string, value interface{}) map[string]interface{} {
-	var replaced bool
-	templateInterface, replaced = utils.ReplaceValueInRequestTemplate(templateInterface, field, value)
-
-	if !replaced {
-		templateInterface["data"] = utils.AddValueToRequestTemplate(templateInterface["data"].(map[string]interface{}), field, value)
-	}
-	return templateInterface
-}
-
 // Terraform call - terraform apply
 // This function updates the state of a vRA 7 Deployment when changes to a Terraform file are applied.
 // The update is performed on the Deployment using supported (day-2) actions.
 func resourceVra7DeploymentUpdate(d *schema.ResourceData, meta interface{}) error {
-	vraClient = meta.(*sdk.APIClient)
+	vraClient, err := deploymentClient(d, meta)
+	if err != nil {
+		return err
+	}
 	// Get the ID of the catalog request that was used to provision this Deployment.
 	catalogItemRequestID := d.Id()
 	// Get client handle
 	p := readProviderConfiguration(d)
 
-	requestTemplate, validityErr := p.checkConfigValuesValidity(d)
+	requestTemplate, validityErr := p.checkConfigValuesValidity(stopContext(meta), vraClient, d)
 	if validityErr != nil {
 		return validityErr
 	}
@@ -238,7 +358,7 @@ func resourceVra7DeploymentUpdate(d *schema.ResourceData, meta interface{}) erro
 		return validityErr
 	}
 
-	resourceActions, err := vraClient.GetResourceActions(catalogItemRequestID)
+	resourceActions, err := vraClient.GetResourceActionsWithContext(stopContext(meta), catalogItemRequestID)
 	if err != nil {
 		log.Errorf("Error while reading resource actions for the request %v: %v ", catalogItemRequestID, err.Error())
 		return fmt.Errorf("Error while reading resource actions for the request %v: %v  ", catalogItemRequestID, err.Error())
@@ -275,7 +395,7 @@ func resourceVra7DeploymentUpdate(d *schema.ResourceData, meta interface{}) erro
 						}
 						log.Info("Retrieving reconfigure action template for the component: %v ", componentName)
 
-						resourceActionTemplate, err := vraClient.GetResourceActionTemplate(resources.ID, reconfigureActionID)
+						resourceActionTemplate, err := vraClient.GetResourceActionTemplateWithContext(stopContext(meta), resources.ID, reconfigureActionID)
 						if err != nil {
 							log.Errorf("Error retrieving reconfigure action template for the component %v: %v ", componentName, err.Error())
 							return fmt.Errorf("Error retrieving reconfigure action template for the component %v: %v ", componentName, err.Error())
@@ -306,7 +426,7 @@ func resourceVra7DeploymentUpdate(d *schema.ResourceData, meta interface{}) erro
 							// This request id is for the reconfigure action on this machine and
 							// will be used to track the status of the reconfigure request for this resource.
 							// It will not replace the initial catalog item request id
-							requestID, err := vraClient.PostResourceAction(resources.ID, reconfigureActionID, resourceActionTemplate)
+							requestID, err := vraClient.PostResourceActionWithContext(stopContext(meta), resources.ID, reconfigureActionID, resourceActionTemplate)
 							if err != nil {
 								err = d.Set("resource_configuration", oldData)
 								if err != nil {
@@ -315,7 +435,7 @@ func resourceVra7DeploymentUpdate(d *schema.ResourceData, meta interface{}) erro
 								log.Errorf("The update request failed with error: %v ", err)
 								return err
 							}
-							status, err := waitForRequestCompletion(d, meta, requestID)
+							status, err := waitForRequestCompletion(d, meta, requestID, schema.TimeoutUpdate)
 							if err != nil {
 								// if the update request fails, go back to the old state and return the error
 								if status == sdk.Failed {
@@ -339,12 +459,28 @@ func resourceVra7DeploymentUpdate(d *schema.ResourceData, meta interface{}) erro
 // This function retrieves the latest state of a vRA 7 deployment. Terraform updates its state based on
 // the information returned by this function.
 func resourceVra7DeploymentRead(d *schema.ResourceData, meta interface{}) error {
-	vraClient = meta.(*sdk.APIClient)
+	vraClient, err := deploymentClient(d, meta)
+	if err != nil {
+		return err
+	}
 	// Get the ID of the catalog request that was used to provision this Deployment. This id
 	// will remain the same for this deployment across any actions on the machines like reconfigure, etc.
 	catalogItemRequestID := d.Id()
 
-	requestResourceView, errTemplate := vraClient.GetRequestResourceView(catalogItemRequestID)
+	if phase := d.Get("request_status").(string); phase != "" && !sdk.IsTerminalPhase(phase) {
+		err = resumePendingRequest(vraClient, d, meta)
+		if err != nil || d.Id() == "" {
+			return err
+		}
+	}
+
+	requestResourceView, errTemplate := vraClient.GetRequestResourceViewWithContext(stopContext(meta), catalogItemRequestID)
+	if sdk.IsNotFound(errTemplate) {
+		// the deployment was deleted outside of terraform, it has to be created again
+		log.Info("The request %v is not found, removing the deployment from the state", catalogItemRequestID)
+		d.SetId("")
+		return nil
+	}
 	if requestResourceView != nil && len(requestResourceView.Content) == 0 {
 		//If resource does not exists then unset the resource ID from state file
 		d.SetId("")
@@ -354,6 +490,122 @@ func resourceVra7DeploymentRead(d *schema.ResourceData, meta interface{}) error
 		return fmt.Errorf("Resource view failed to load:  %v", errTemplate)
 	}
 
+	err = d.Set("resources", deploymentResources(requestResourceView))
+	if err != nil {
+		return err
+	}
+
+	resourceDataMap := machineResourceData(requestResourceView)
+	resourceConfiguration, _ := d.Get("resource_configuration").(map[string]interface{})
+	changed := false
+
+	resourceConfiguration, changed = utils.UpdateResourceConfigurationMap(resourceConfiguration, resourceDataMap)
+
+	if changed {
+		setError := d.Set("resource_configuration", resourceConfiguration)
+		if setError != nil {
+			return setError
+		}
+	}
+	return nil
+}
+
+// Terraform call - terraform import
+// This function brings an existing vRA 7 deployment under management. The deployment is identified by
+// the id of the catalog request which provisioned it, or by its own id or name. The id of a deployment
+// of another tenant than the one of the provider is prefixed with the tenant, like <tenant>/<id>.
+func resourceVra7DeploymentImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	// the id is prefixed with the tenant of the deployment only when the prefix is a tenant,
+	// the names of the deployments may contain slashes too
+	if i := strings.Index(d.Id(), "/"); i > 0 {
+		tenant := d.Id()[:i]
+		isTenant, err := meta.(*providerMeta).client.IsTenant(stopContext(meta), tenant)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to import the deployment %s: %v", d.Id(), err)
+		}
+		if isTenant {
+			d.Set("tenant", tenant)
+			d.SetId(d.Id()[i+1:])
+		}
+	}
+	vraClient, err := deploymentClient(d, meta)
+	if err != nil {
+		return nil, err
+	}
+	request, err := findDeploymentRequest(stopContext(meta), vraClient, d.Id())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to import the deployment %s: %v", d.Id(), err)
+	}
+	log.Info("Importing the deployment provisioned by the request %s", request.ID)
+	d.SetId(request.ID)
+	d.Set("catalog_item_id", request.CatalogItemRef.ID)
+	d.Set("catalog_item_name", request.CatalogItemRef.Label)
+	d.Set("businessgroup_id", request.Organization.SubtenantRef)
+	d.Set("businessgroup_name", request.Organization.SubtenantLabel)
+	d.Set("description", request.Description)
+	if reasons, ok := request.Reasons.(string); ok {
+		d.Set("reasons", reasons)
+	}
+	d.Set("request_status", request.Phase)
+
+	requestResourceView, err := vraClient.GetRequestResourceViewWithContext(stopContext(meta), request.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the resources of the request %s: %v", request.ID, err)
+	}
+	// only the properties a user can set are imported, the computed ones like the ip address
+	// would differ from the configuration
+	resourceConfiguration := make(map[string]interface{})
+	for componentName, dataVals := range machineResourceData(requestResourceView) {
+		for _, propertyName := range configurableMachineProperties {
+			if stringValue := utils.ConvertInterfaceToString(dataVals[propertyName]); stringValue != "" {
+				resourceConfiguration[componentName+"."+propertyName] = stringValue
+			}
+		}
+	}
+	err = d.Set("resource_configuration", resourceConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// configurableMachineProperties are the properties of the machines which are imported into the
+// resource_configuration. vRA 7 does not return the other properties of the components, like
+// their custom properties, with the resources of a deployment, so they cannot be imported.
+var configurableMachineProperties = []string{sdk.MachineCPU, sdk.MachineMemory, sdk.MachineStorage}
+
+// findDeploymentRequest returns the catalog request which provisioned the deployment
+// identified by the id of the request, or by the id or the name of the deployment
+func findDeploymentRequest(ctx context.Context, vraClient *sdk.APIClient, id string) (*sdk.CatalogRequest, error) {
+	request, err := vraClient.GetRequest(ctx, id)
+	if !isUnknownID(err) {
+		return request, err
+	}
+	resource, err := vraClient.GetResource(ctx, id)
+	if isUnknownID(err) {
+		resource, err = vraClient.FindDeploymentByName(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resource.RequestID == "" {
+		return nil, fmt.Errorf("The resource %s was not provisioned by a catalog request", id)
+	}
+	return vraClient.GetRequest(ctx, resource.RequestID)
+}
+
+// isUnknownID returns true if the error means that there is no object with the given id,
+// or that the id is not in the format of the ids of these objects
+func isUnknownID(err error) bool {
+	if sdk.IsNotFound(err) {
+		return true
+	}
+	apiErr, ok := sdk.AsAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusBadRequest
+}
+
+// machineResourceData returns the properties of the machines of a deployment, by component
+func machineResourceData(requestResourceView *sdk.RequestResourceView) map[string]map[string]interface{} {
 	resourceDataMap := make(map[string]map[string]interface{})
 	for _, resource := range requestResourceView.Content {
 		if resource.ResourceType == sdk.InfrastructureVirtual {
@@ -376,24 +628,62 @@ func resourceVra7DeploymentRead(d *schema.ResourceData, meta interface{}) error
 			dataVals[sdk.MachineDestructionDate] = resourceData.MachineDestructionDate
 		}
 	}
-	resourceConfiguration, _ := d.Get("resource_configuration").(map[string]interface{})
-	changed := false
-
-	resourceConfiguration, changed = utils.UpdateResourceConfigurationMap(resourceConfiguration, resourceDataMap)
+	return resourceDataMap
+}
 
-	if changed {
-		setError := d.Set("resource_configuration", resourceConfiguration)
-		if setError != nil {
-			return fmt.Errorf(setError.Error())
+// deploymentResources describes every resource provisioned by the request of the deployment,
+// for the resources attribute
+func deploymentResources(requestResourceView *sdk.RequestResourceView) []map[string]interface{} {
+	resources := make([]map[string]interface{}, 0, len(requestResourceView.Content))
+	for _, resource := range requestResourceView.Content {
+		resourceData := resource.ResourcesData
+		var ipAddresses []string
+		for _, ipAddress := range strings.Split(resourceData.IPAddress, ",") {
+			if ipAddress = strings.TrimSpace(ipAddress); ipAddress != "" {
+				ipAddresses = append(ipAddresses, ipAddress)
+			}
+		}
+		var leaseStart, leaseEnd string
+		if resource.Lease != nil {
+			leaseStart = resource.Lease.Start
+			leaseEnd = resource.Lease.End
 		}
+		resources = append(resources, map[string]interface{}{
+			"component_name": resourceData.Component,
+			"resource_id":    resource.ResourceID,
+			"resource_type":  resource.ResourceType,
+			"name":           resource.Name,
+			"status":         resource.Status,
+			"machine_name":   resourceData.MachineName,
+			"ip_addresses":   ipAddresses,
+			"cpu":            resourceData.CPU,
+			"memory":         resourceData.Memory,
+			"storage":        resourceData.Storage,
+			"guest_os":       resourceData.MachineGuestOperatingSystem,
+			"lease_start":    leaseStart,
+			"lease_end":      leaseEnd,
+		})
 	}
-	return nil
+	// vRA lists the resources in no particular order, the deployment comes first and its
+	// components follow by name so that the resources keep their index from one refresh to the next
+	sort.SliceStable(resources, func(i, j int) bool {
+		iDeployment := resources[i]["resource_type"] == sdk.DeploymentResourceType
+		jDeployment := resources[j]["resource_type"] == sdk.DeploymentResourceType
+		if iDeployment != jDeployment {
+			return iDeployment
+		}
+		return resources[i]["component_name"].(string) < resources[j]["component_name"].(string)
+	})
+	return resources
 }
 
 //Function use - To delete resources which are created by terraform and present in state file
 //Terraform call - terraform destroy
 func resourceVra7DeploymentDelete(d *schema.ResourceData, meta interface{}) error {
-	vraClient = meta.(*sdk.APIClient)
+	vraClient, err := deploymentClient(d, meta)
+	if err != nil {
+		return err
+	}
 	//Get requester machine ID from schema.dataresource
 	catalogItemRequestID := d.Id()
 	// Throw an error if request ID has no value or empty value
@@ -402,7 +692,13 @@ func resourceVra7DeploymentDelete(d *schema.ResourceData, meta interface{}) erro
 	}
 	log.Info("Calling delete resource for the request id %v ", catalogItemRequestID)
 
-	resourceView, err := vraClient.GetRequestResourceView(catalogItemRequestID)
+	resourceView, err := vraClient.GetRequestResourceViewWithContext(stopContext(meta), catalogItemRequestID)
+	if sdk.IsNotFound(err) {
+		// the deployment is already gone
+		log.Info("The request %v is not found, the deployment was already deleted", catalogItemRequestID)
+		d.SetId("")
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("Resource view failed to load:  %v", err)
 	}
@@ -412,7 +708,7 @@ func resourceVra7DeploymentDelete(d *schema.ResourceData, meta interface{}) erro
 		return fmt.Errorf("The resource cannot be found")
 	}
 
-	resourceActions, err := vraClient.GetResourceActions(catalogItemRequestID)
+	resourceActions, err := vraClient.GetResourceActionsWithContext(stopContext(meta), catalogItemRequestID)
 	if err != nil {
 		return err
 	}
@@ -434,21 +730,20 @@ func resourceVra7DeploymentDelete(d *schema.ResourceData, meta interface{}) erro
 			if !destroyEnabled {
 				return fmt.Errorf("The deployment %v cannot be destroyed, your entitlement has no Destroy Deployment action enabled", deploymentName)
 			}
-			resourceActionTemplate, err := vraClient.GetResourceActionTemplate(resources.ID, destroyActionID)
+			resourceActionTemplate, err := vraClient.GetResourceActionTemplateWithContext(stopContext(meta), resources.ID, destroyActionID)
 			if err != nil {
 				log.Errorf(DestroyActionTemplateError, deploymentName, err.Error())
 				return fmt.Errorf(DestroyActionTemplateError, deploymentName, err.Error())
 			}
-			requestID, err := vraClient.PostResourceAction(resources.ID, destroyActionID, resourceActionTemplate)
+			requestID, err := vraClient.PostResourceActionWithContext(stopContext(meta), resources.ID, destroyActionID, resourceActionTemplate)
 			if err != nil {
 				log.Errorf("The destroy deployment request failed with error: %v ", err)
 				return err
 			}
-			status, err := waitForRequestCompletion(d, meta, requestID)
+			// the deployment is kept in the state unless its destroy request has succeeded
+			_, err = waitForRequestCompletion(d, meta, requestID, schema.TimeoutDelete)
 			if err != nil {
-				if status == sdk.Successful {
-					d.SetId("")
-				}
+				return err
 			}
 		}
 	}
@@ -461,10 +756,8 @@ func (p *ProviderSchema) checkResourceConfigValidity(requestTemplate *sdk.Catalo
 
 	// Get all component names in the blueprint corresponding to the catalog item.
 	componentSet := make(map[string]bool)
-	for field := range requestTemplate.Data {
-		if reflect.ValueOf(requestTemplate.Data[field]).Kind() == reflect.Map {
-			componentSet[field] = true
-		}
+	for _, componentName := range requestTemplate.ComponentNames() {
+		componentSet[componentName] = true
 	}
 	log.Info("The component name(s) in the blueprint corresponding to the catalog item: %v\n", componentSet)
 
@@ -499,7 +792,7 @@ func (p *ProviderSchema) checkResourceConfigValidity(requestTemplate *sdk.Catalo
 
 // check if the values provided in the config file are valid and set
 // them in the resource schema. Requires to call APIs
-func (p *ProviderSchema) checkConfigValuesValidity(d *schema.ResourceData) (*sdk.CatalogItemRequestTemplate, error) {
+func (p *ProviderSchema) checkConfigValuesValidity(ctx context.Context, vraClient *sdk.APIClient, d *schema.ResourceData) (*sdk.CatalogItemRequestTemplate, error) {
 	// 	// If catalog_name and catalog_id both not provided then return an error
 	if len(p.CatalogItemName) <= 0 && len(p.CatalogItemID) <= 0 {
 		return nil, fmt.Errorf("Either catalog_name or catalog_id should be present in given configuration")
@@ -510,7 +803,7 @@ func (p *ProviderSchema) checkConfigValuesValidity(d *schema.ResourceData) (*sdk
 	var err error
 	// if catalog item id is provided, fetch the catalog item name
 	if len(p.CatalogItemName) > 0 {
-		catalogItemIDFromName, err = vraClient.ReadCatalogItemByName(p.CatalogItemName)
+		catalogItemIDFromName, err = vraClient.ReadCatalogItemByNameWithContext(ctx, p.CatalogItemName)
 		if err != nil || catalogItemIDFromName == "" {
 			return nil, fmt.Errorf("Error in finding catalog item id corresponding to the catlog item name %v: \n %v", p.CatalogItemName, err)
 		}
@@ -519,7 +812,7 @@ func (p *ProviderSchema) checkConfigValuesValidity(d *schema.ResourceData) (*sdk
 
 	// if catalog item name is provided, fetch the catalog item id
 	if len(p.CatalogItemID) > 0 { // else if both are provided and matches or just id is provided, use id
-		catalogItemNameFromID, err = vraClient.ReadCatalogItemNameByID(p.CatalogItemID)
+		catalogItemNameFromID, err = vraClient.ReadCatalogItemNameByIDWithContext(ctx, p.CatalogItemID)
 		if err != nil || catalogItemNameFromID == "" {
 			return nil, fmt.Errorf("Error in finding catalog item name corresponding to the catlog item id %v: \n %v", p.CatalogItemID, err)
 		}
@@ -542,7 +835,7 @@ func (p *ProviderSchema) checkConfigValuesValidity(d *schema.ResourceData) (*sdk
 	p.CatalogItemID = d.Get("catalog_item_id").(string)
 
 	// Get request template for catalog item.
-	requestTemplate, err := vraClient.GetCatalogItemRequestTemplate(p.CatalogItemID)
+	requestTemplate, err := vraClient.GetCatalogItemRequestTemplateWithContext(ctx, p.CatalogItemID)
 	if err != nil {
 		return nil, err
 	}
@@ -555,7 +848,7 @@ func (p *ProviderSchema) checkConfigValuesValidity(d *schema.ResourceData) (*sdk
 	// get the business group id from name
 	var businessGroupIDFromName string
 	if len(p.BusinessGroupName) > 0 {
-		businessGroupIDFromName, err = vraClient.GetBusinessGroupID(p.BusinessGroupName, vraClient.Tenant)
+		businessGroupIDFromName, err = vraClient.GetBusinessGroupIDWithContext(ctx, p.BusinessGroupName, vraClient.Tenant)
 		if err != nil || businessGroupIDFromName == "" {
 			return nil, err
 		}
@@ -575,36 +868,166 @@ func (p *ProviderSchema) checkConfigValuesValidity(d *schema.ResourceData) (*sdk
 	return requestTemplate, nil
 }
 
-// check the request status on apply and update
-func waitForRequestCompletion(d *schema.ResourceData, meta interface{}, requestID string) (string, error) {
-
-	waitTimeout := d.Get("wait_timeout").(int) * 60
-	sleepFor := 30
-	requestStatus := ""
-	for i := 0; i < waitTimeout/sleepFor; i++ {
-		log.Info("Waiting for %d seconds before checking request status.", sleepFor)
-		time.Sleep(time.Duration(sleepFor) * time.Second)
-
-		reqestStatusView, _ := vraClient.GetRequestStatus(requestID)
-		status := reqestStatusView.Phase
-		d.Set("request_status", status)
-		log.Info("Checking to see the status of the request. Status: %s.", requestStatus)
-		if status == sdk.Successful {
-			log.Info("Request is SUCCESSFUL.")
-			return sdk.Successful, nil
-		} else if status == sdk.Failed {
-			log.Error("Request Failed with message %v ", d.Get("failed_message"))
-			return sdk.Failed, fmt.Errorf("Request failed \n %v ", d.Get("failed_message"))
-		} else if requestStatus == sdk.InProgress {
-			log.Info("The request is still IN PROGRESS. Please try again later. \nRun terraform refresh to get the latest state of your request")
-			return sdk.InProgress, nil
-		} else {
-			log.Info("Request status: %s.", requestStatus)
+// check the request status on apply and update, for at most the timeout of the operation
+func waitForRequestCompletion(d *schema.ResourceData, meta interface{}, requestID string, timeoutKey string) (string, error) {
+	vraClient, err := deploymentClient(d, meta)
+	if err != nil {
+		return "", err
+	}
+	ctx := stopContext(meta)
+	opts := sdk.DefaultWaitOptions()
+	opts.PollInterval = pollInterval(d)
+	opts.InitialDelay = opts.PollInterval
+	opts.MaxDuration = requestTimeout(d, d.Timeout(timeoutKey), d.Timeout(schema.TimeoutDefault))
+	opts.OnProgress = func(status *sdk.RequestStatusView) {
+		log.Info("Checking to see the status of the request. Status: %s.", status.Phase)
+		// request_status is the phase of the catalog request of the deployment, not the
+		// one of the requests of its day-2 actions
+		if requestID == d.Id() {
+			d.Set("request_status", status.Phase)
+		}
+	}
+
+	result, err := vraClient.WaitForRequest(ctx, requestID, opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", &requestPendingError{fmt.Sprintf(WaitInterruptedError, requestID)}
+		}
+		if _, ok := err.(*sdk.WaitTimeoutError); ok {
+			// The execution has timed out while still IN PROGRESS.
+			// The user will need to use 'terraform refresh' at a later point to resolve this.
+			return "", &requestPendingError{WaitTimeoutError}
 		}
+		return "", fmt.Errorf("Unable to read the status of the request %s: %v", requestID, err)
+	}
+	if err := vraClient.RequestError(ctx, result); err != nil {
+		log.Error("Request Failed with message %v ", result.CompletionDetails)
+		d.Set("failed_message", result.CompletionDetails)
+		return result.Phase, err
+	}
+	log.Info("Request is SUCCESSFUL.")
+	d.Set("failed_message", "")
+	return sdk.Successful, nil
+}
+
+// deploymentClient returns the client of the tenant of the deployment, which is the
+// tenant of the provider unless the deployment has its own
+func deploymentClient(d *schema.ResourceData, meta interface{}) (*sdk.APIClient, error) {
+	vraClient, err := meta.(*providerMeta).client.ForTenant(d.Get("tenant").(string))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid tenant: %v", err)
+	}
+	return vraClient, nil
+}
+
+// requestTimeout returns how long to wait for the request of an operation, given the timeout of
+// the operation and the default timeout of the timeouts block, which are 0 when the block does not
+// set them. The deprecated wait_timeout in minutes is only a fallback, used when the timeouts block
+// sets neither.
+func requestTimeout(d *schema.ResourceData, timeout, defaultTimeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	if defaultTimeout > 0 {
+		return defaultTimeout
+	}
+	if waitTimeout, ok := d.GetOk("wait_timeout"); ok {
+		return time.Duration(waitTimeout.(int)) * time.Minute
+	}
+	return DefaultRequestTimeout
+}
+
+// pollInterval returns the wait between two checks of the status of a request, poll_interval
+// has no default in the schema so that the states which do not have it show no diff
+func pollInterval(d *schema.ResourceData) time.Duration {
+	if interval, ok := d.GetOk("poll_interval"); ok {
+		return time.Duration(interval.(int)) * time.Second
+	}
+	return sdk.DefaultWaitPollInterval
+}
+
+// resumePendingRequest waits for the catalog request of a deployment whose creation did not
+// wait until the request completed, so that the deployment is adopted once the request has
+// succeeded rather than requested again. A deployment whose request did not succeed is
+// removed from the state.
+func resumePendingRequest(vraClient *sdk.APIClient, d *schema.ResourceData, meta interface{}) error {
+	requestID := d.Id()
+	status, err := vraClient.GetRequestStatusWithContext(stopContext(meta), requestID)
+	if sdk.IsNotFound(err) {
+		log.Info("The request %v is not found, removing the deployment from the state", requestID)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read the status of the request %s: %v", requestID, err)
+	}
+	phase := status.Phase
+	switch {
+	case !sdk.IsTerminalPhase(phase):
+		log.Info("The request %s is still %s, waiting for it to complete", requestID, phase)
+		phase, err = waitForRequestCompletion(d, meta, requestID, schema.TimeoutCreate)
+		if err != nil && !sdk.IsTerminalPhase(phase) {
+			return err
+		}
+	case phase != sdk.Successful:
+		d.Set("failed_message", status.RequestCompletion.CompletionDetails)
+	}
+	d.Set("request_status", phase)
+	if phase != sdk.Successful {
+		log.Warning("The request %s is %s, removing the deployment from the state", requestID, phase)
+		d.SetId("")
+	}
+	return nil
+}
+
+// requestPendingError is returned by waitForRequestCompletion when the wait has timed out
+// or was interrupted before the request has completed
+type requestPendingError struct {
+	message string
+}
+
+func (e *requestPendingError) Error() string {
+	return e.message
+}
+
+// cancelPendingRequest cancels a catalog request which did not complete in time, so that
+// it does not provision a deployment terraform does not know about. The request is removed
+// from the state once it has stopped without succeeding. vRA may reject the cancellation, or
+// accept it and still complete a request which is already provisioning: the id of the request
+// is then kept in the state to get the result of the request later.
+func cancelPendingRequest(vraClient *sdk.APIClient, d *schema.ResourceData, requestID string, waitErr error) error {
+	// the stop context may be cancelled already, the cancellation has its own timeout
+	ctx, cancel := context.WithTimeout(context.Background(), CancelTimeout)
+	defer cancel()
+
+	log.Info("Cancelling the request %s: %v", requestID, waitErr)
+	err := vraClient.CancelRequest(ctx, requestID)
+	if err != nil {
+		log.Warning("Unable to cancel the request %s: %v", requestID, err)
+		return fmt.Errorf(RequestNotCancelledError, requestID, err)
+	}
+
+	opts := sdk.DefaultWaitOptions()
+	opts.InitialDelay = 0
+	opts.PollInterval = pollInterval(d)
+	result, err := vraClient.WaitForRequest(ctx, requestID, opts)
+	if result.Phase != "" {
+		d.Set("request_status", result.Phase)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("the request has not stopped after %v", CancelTimeout)
+		}
+		log.Warning("The request %s has not stopped after its cancellation: %v", requestID, err)
+		return fmt.Errorf(RequestNotCancelledError, requestID, err)
+	}
+	if result.Succeeded() {
+		log.Warning("The request %s has completed successfully before it could be cancelled", requestID)
+		return nil
 	}
-	// The execution has timed out while still IN PROGRESS.
-	// The user will need to use 'terraform refresh' at a later point to resolve this.
-	return "", fmt.Errorf("Request has timed out. Please try again later. \nRun terraform refresh to get the latest state of your request")
+	d.Set("failed_message", result.CompletionDetails)
+	d.SetId("")
+	return fmt.Errorf(RequestCancelledError, requestID, waitErr)
 }
 
 // read the config file
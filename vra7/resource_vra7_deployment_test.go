@@ -1,12 +1,16 @@
 package vra7
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/terraform"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -21,6 +25,20 @@ func init() {
 	client = sdk.NewClient(mockUser, mockPassword, mockTenant, mockBaseURL, insecureBool)
 }
 
+// mockMeta returns the meta the provider hands to its resources, with the given client
+func mockMeta(c *sdk.APIClient) *providerMeta {
+	return &providerMeta{client: c, stopContext: context.Background()}
+}
+
+// mockClient returns a client which has logged in and whose requests are answered by the
+// given fake
+func mockClient(fake *fakeVRA) *sdk.APIClient {
+	c := sdk.NewClient(mockUser, mockPassword, mockTenant, mockBaseURL, true)
+	c.BearerToken = "Bearer mock-token"
+	c.HTTPClient = fake
+	return c
+}
+
 func TestConfigValidityFunction(t *testing.T) {
 
 	mockRequestTemplate := GetMockRequestTemplate()
@@ -80,6 +98,438 @@ func TestConfigValidityFunction(t *testing.T) {
 	utils.AssertEqualsString(t, validityErr, err.Error())
 }
 
+func TestReadDeletedDeployment(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	url := c.BuildEncodedURL(fmt.Sprintf(sdk.GetRequestResourceViewAPI, mockRequestID), nil)
+	fake.register("GET "+url, stringResponse(400, requestNotFoundResponse))
+
+	mockResourceData := schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{})
+	mockResourceData.SetId(mockRequestID)
+
+	// a deployment deleted outside of terraform is removed from the state
+	err := resourceVra7DeploymentRead(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "", mockResourceData.Id())
+
+	// other errors fail the refresh
+	fake = stubVRA(map[string]*sdk.APIResponse{})
+	c.HTTPClient = fake
+	fake.register("GET "+url, stringResponse(500, systemExceptionResponse))
+	mockResourceData.SetId(mockRequestID)
+	err = resourceVra7DeploymentRead(mockResourceData, mockMeta(c))
+	utils.AssertNotNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, mockResourceData.Id())
+}
+
+// fakeVRA is an HTTPClient which answers the requests with the responses registered for
+// their method and url, or for their url without its query, or with a 404. It counts the
+// requests it answers.
+type fakeVRA struct {
+	lock      sync.Mutex
+	responses map[string]*sdk.APIResponse
+	calls     map[string]int
+}
+
+// stubVRA returns a fakeVRA which answers with the responses of the given method and url
+func stubVRA(responses map[string]*sdk.APIResponse) *fakeVRA {
+	return &fakeVRA{responses: responses, calls: make(map[string]int)}
+}
+
+// DoRequest answers the request with its registered response
+func (f *fakeVRA) DoRequest(req *sdk.APIRequest) (*sdk.APIResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := req.Method + " " + req.URL
+	f.calls[key]++
+	if resp, ok := f.responses[key]; ok {
+		return resp, nil
+	}
+	if i := strings.Index(key, "?"); i >= 0 {
+		if resp, ok := f.responses[key[:i]]; ok {
+			return resp, nil
+		}
+	}
+	return &sdk.APIResponse{Status: "404 Not Found", StatusCode: 404, Body: []byte(requestNotFoundResponse)}, nil
+}
+
+// register answers the requests of the given method and url with the response
+func (f *fakeVRA) register(key string, resp *sdk.APIResponse) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.responses[key] = resp
+}
+
+// callCount returns the number of requests of the given method and url answered so far
+func (f *fakeVRA) callCount(key string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.calls[key]
+}
+
+func stringResponse(statusCode int, body string) *sdk.APIResponse {
+	return &sdk.APIResponse{Status: strconv.Itoa(statusCode), StatusCode: statusCode, Body: []byte(body)}
+}
+
+func TestCancelPendingRequest(t *testing.T) {
+	vraClient := sdk.NewClient(mockUser, mockPassword, mockTenant, mockBaseURL, true)
+	vraClient.BearerToken = "Bearer mock-token"
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	cancelURL := "POST " + vraClient.BuildEncodedURL(fmt.Sprintf(sdk.CancelRequestAPI, mockRequestID), nil)
+	statusURL := "GET " + vraClient.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockRequestID, nil)
+
+	mockResourceData := schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{
+		"cancel_on_timeout": true,
+		"poll_interval":     1,
+	})
+	mockResourceData.SetId(mockRequestID)
+
+	// a request which has stopped after its cancellation is removed from the state
+	vraClient.HTTPClient = stubVRA(map[string]*sdk.APIResponse{
+		cancelURL: stringResponse(200, ""),
+		statusURL: stringResponse(200, `{"phase":"FAILED","requestCompletion":{"requestCompletionState":"FAILED","CompletionDetails":"Request cancelled."}}`),
+	})
+	err := cancelPendingRequest(vraClient, mockResourceData, mockRequestID, &requestPendingError{WaitTimeoutError})
+	utils.AssertNotNilError(t, err)
+	utils.AssertContainsString(t, "has been cancelled", err.Error())
+	utils.AssertEqualsString(t, "", mockResourceData.Id())
+	utils.AssertEqualsString(t, sdk.Failed, mockResourceData.Get("request_status").(string))
+
+	// a request which cannot be cancelled anymore is kept
+	vraClient.HTTPClient = stubVRA(map[string]*sdk.APIResponse{
+		cancelURL: stringResponse(400, requestNotCancellableResponse),
+	})
+	mockResourceData.SetId(mockRequestID)
+	err = cancelPendingRequest(vraClient, mockResourceData, mockRequestID, &requestPendingError{WaitTimeoutError})
+	utils.AssertNotNilError(t, err)
+	utils.AssertContainsString(t, "could not be cancelled", err.Error())
+	utils.AssertEqualsString(t, mockRequestID, mockResourceData.Id())
+
+	// and so is a request which completes although vRA has accepted its cancellation
+	vraClient.HTTPClient = stubVRA(map[string]*sdk.APIResponse{
+		cancelURL: stringResponse(200, ""),
+		statusURL: stringResponse(200, `{"phase":"SUCCESSFUL","requestCompletion":{"requestCompletionState":"SUCCESSFUL"}}`),
+	})
+	err = cancelPendingRequest(vraClient, mockResourceData, mockRequestID, &requestPendingError{WaitTimeoutError})
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, mockResourceData.Id())
+	utils.AssertEqualsString(t, sdk.Successful, mockResourceData.Get("request_status").(string))
+}
+
+func TestCreatePendingRequest(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	mockCatalogItemID := "feaedf73-560c-4612-a573-41667e017691"
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	fake.register("GET "+c.BuildEncodedURL(sdk.EntitledCatalogItems+"/"+mockCatalogItemID, nil),
+		stringResponse(200, `{"catalogItem":{"name":"Prativa_CentOs","catalogItemId":"`+mockCatalogItemID+`"}}`))
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.RequestTemplateAPI, mockCatalogItemID), nil),
+		stringResponse(200, mockRequestTemplate))
+	// the catalog item id of mockRequestTemplate
+	fake.register("POST "+c.BuildEncodedURL(sdk.EntitledCatalogItems+"/dhbh-jhdv-ghdv-dhvdd/requests", nil),
+		stringResponse(201, `{"id":"`+mockRequestID+`","phase":"PENDING_PRE_APPROVAL"}`))
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockRequestID, nil),
+		stringResponse(200, `{"id":"`+mockRequestID+`","phase":"IN_PROGRESS"}`))
+
+	// the resource is created through Apply, which sets up the timeouts
+	diff := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"catalog_item_id": {New: mockCatalogItemID},
+			"poll_interval":   {New: "1"},
+		},
+		Meta: map[string]interface{}{
+			schema.TimeoutKey: map[string]interface{}{schema.TimeoutCreate: time.Second},
+		},
+	}
+
+	// a request which is still running at the end of the timeout does not fail the creation,
+	// it is kept in the state to be waited for by the next refresh
+	state, err := resourceVra7Deployment().Apply(nil, diff, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, state.ID)
+	utils.AssertFalse(t, "the request status is not final", sdk.IsTerminalPhase(state.Attributes["request_status"]))
+}
+
+func TestReadResumesPendingRequest(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	requestURL := c.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockRequestID, nil)
+	fake.register("GET "+requestURL, stringResponse(200, catalogRequestResponse))
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetRequestResourceViewAPI, mockRequestID), nil),
+		stringResponse(200, requestResourceViewResponse))
+
+	// the request the creation did not wait for has succeeded since, the deployment is adopted
+	mockResourceData := schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{
+		"resource_configuration": map[string]interface{}{
+			"vSphereVM1.cpu": "1",
+		},
+	})
+	mockResourceData.SetId(mockRequestID)
+	mockResourceData.Set("request_status", sdk.InProgress)
+	err := resourceVra7DeploymentRead(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, mockResourceData.Id())
+	utils.AssertEqualsString(t, sdk.Successful, mockResourceData.Get("request_status").(string))
+	resourceConfiguration := mockResourceData.Get("resource_configuration").(map[string]interface{})
+	utils.AssertEqualsString(t, "2", resourceConfiguration["vSphereVM1.cpu"].(string))
+
+	// a deployment whose request has failed is removed from the state, to be requested again
+	fake.register("GET "+requestURL, stringResponse(200, failedRequestStatusResponse))
+	mockResourceData.Set("request_status", sdk.InProgress)
+	err = resourceVra7DeploymentRead(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "", mockResourceData.Id())
+	utils.AssertEqualsString(t, sdk.Failed, mockResourceData.Get("request_status").(string))
+	utils.AssertContainsString(t, "CloneVM_Task", mockResourceData.Get("failed_message").(string))
+
+	// a deployment which has completed is not waited for
+	fake = stubVRA(map[string]*sdk.APIResponse{})
+	c.HTTPClient = fake
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetRequestResourceViewAPI, mockRequestID), nil),
+		stringResponse(200, requestResourceViewResponse))
+	mockResourceData.SetId(mockRequestID)
+	mockResourceData.Set("request_status", sdk.Successful)
+	err = resourceVra7DeploymentRead(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 0, fake.callCount("GET "+requestURL))
+}
+
+func TestReadDeploymentResources(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetRequestResourceViewAPI, mockRequestID), nil),
+		stringResponse(200, requestResourceViewResponse))
+
+	// every resource is described, whether the resource_configuration mentions it or not
+	mockResourceData := schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{})
+	mockResourceData.SetId(mockRequestID)
+	err := resourceVra7DeploymentRead(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsInt(t, 2, mockResourceData.Get("resources.#").(int))
+	utils.AssertEqualsString(t, sdk.DeploymentResourceType, mockResourceData.Get("resources.0.resource_type").(string))
+	utils.AssertEqualsString(t, "Prativa_CentOs-86390713", mockResourceData.Get("resources.0.name").(string))
+
+	utils.AssertEqualsString(t, "vSphereVM1", mockResourceData.Get("resources.1.component_name").(string))
+	utils.AssertEqualsString(t, "4a7a33a8-6b21-461c-a618-442044059ef8", mockResourceData.Get("resources.1.resource_id").(string))
+	utils.AssertEqualsString(t, "On", mockResourceData.Get("resources.1.status").(string))
+	utils.AssertEqualsString(t, "Development0231", mockResourceData.Get("resources.1.machine_name").(string))
+	utils.AssertEqualsString(t, "10.112.4.231", mockResourceData.Get("resources.1.ip_addresses.0").(string))
+	utils.AssertEqualsInt(t, 2, mockResourceData.Get("resources.1.cpu").(int))
+	utils.AssertEqualsInt(t, 2048, mockResourceData.Get("resources.1.memory").(int))
+	utils.AssertEqualsInt(t, 16, mockResourceData.Get("resources.1.storage").(int))
+	utils.AssertEqualsString(t, "CentOS 4/5/6/7 (64-bit)", mockResourceData.Get("resources.1.guest_os").(string))
+	utils.AssertEqualsString(t, "2019-03-04T00:11:12.040Z", mockResourceData.Get("resources.1.lease_end").(string))
+}
+
+func TestDeploymentResourcesOrder(t *testing.T) {
+	// the deployment comes first whatever the order vRA lists the resources in, and its
+	// components follow by name
+	resources := deploymentResources(&sdk.RequestResourceView{Content: []sdk.DeploymentResource{
+		{ResourceType: "Infrastructure.Virtual", ResourcesData: sdk.DeploymentResourceData{Component: "vSphereVM2"}},
+		{ResourceType: "Infrastructure.Virtual", ResourcesData: sdk.DeploymentResourceData{Component: "vSphereVM1"}},
+		{ResourceType: sdk.DeploymentResourceType, Name: "Prativa_CentOs-86390713"},
+	}})
+	utils.AssertEqualsInt(t, 3, len(resources))
+	utils.AssertEqualsString(t, "Prativa_CentOs-86390713", resources[0]["name"].(string))
+	utils.AssertEqualsString(t, "vSphereVM1", resources[1]["component_name"].(string))
+	utils.AssertEqualsString(t, "vSphereVM2", resources[2]["component_name"].(string))
+}
+
+func TestDeploymentClient(t *testing.T) {
+	resourceSchema := resourceVra7Deployment().Schema
+
+	// the deployments are in the tenant of the provider by default
+	mockResourceData := schema.TestResourceDataRaw(t, resourceSchema, map[string]interface{}{})
+	vraClient, err := deploymentClient(mockResourceData, mockMeta(client))
+	utils.AssertNilError(t, err)
+	utils.AssertTrue(t, "the client of the provider", vraClient == client)
+
+	mockResourceData = schema.TestResourceDataRaw(t, resourceSchema, map[string]interface{}{
+		"tenant": "finance",
+	})
+	vraClient, err = deploymentClient(mockResourceData, mockMeta(client))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, "finance", vraClient.Tenant)
+	utils.AssertEqualsString(t, client.Username, vraClient.Username)
+}
+
+// configTimeouts returns the timeouts terraform passes to the operations of a deployment
+// with this configuration
+func configTimeouts(t *testing.T, raw map[string]interface{}) *schema.ResourceTimeout {
+	rawConfig, err := config.NewRawConfig(raw)
+	utils.AssertNilError(t, err)
+	diff, err := resourceVra7Deployment().Diff(nil, terraform.NewResourceConfig(rawConfig))
+	utils.AssertNilError(t, err)
+	timeouts := &schema.ResourceTimeout{}
+	utils.AssertNilError(t, timeouts.DiffDecode(diff))
+	return timeouts
+}
+
+func TestRequestTimeout(t *testing.T) {
+	resourceSchema := resourceVra7Deployment().Schema
+
+	mockResourceData := schema.TestResourceDataRaw(t, resourceSchema, map[string]interface{}{})
+	utils.AssertTrue(t, "the default poll interval", pollInterval(mockResourceData) == sdk.DefaultWaitPollInterval)
+	utils.AssertEqualsInt(t, 15, mockResourceData.Get("wait_timeout").(int))
+	timeouts := configTimeouts(t, map[string]interface{}{"catalog_item_name": "CentOS 7.0 x64"})
+	utils.AssertTrue(t, "the default timeout", requestTimeout(mockResourceData, *timeouts.Create, *timeouts.Default) == DefaultRequestTimeout)
+
+	// the deprecated wait_timeout is used when the timeouts block does not set the timeout
+	raw := map[string]interface{}{"catalog_item_name": "CentOS 7.0 x64", "wait_timeout": 5}
+	mockResourceData = schema.TestResourceDataRaw(t, resourceSchema, raw)
+	timeouts = configTimeouts(t, raw)
+	utils.AssertTrue(t, "the wait_timeout", requestTimeout(mockResourceData, *timeouts.Create, *timeouts.Default) == 5*time.Minute)
+
+	// but the timeouts block takes precedence, even when it sets the default timeout
+	raw["timeouts"] = []map[string]interface{}{{"create": "15m", "default": "40m"}}
+	timeouts = configTimeouts(t, raw)
+	utils.AssertTrue(t, "the create timeout", requestTimeout(mockResourceData, *timeouts.Create, *timeouts.Default) == DefaultRequestTimeout)
+	utils.AssertTrue(t, "the default of the timeouts block", requestTimeout(mockResourceData, *timeouts.Delete, *timeouts.Default) == 40*time.Minute)
+
+	_, errs := resourceSchema["poll_interval"].ValidateFunc(0, "poll_interval")
+	utils.AssertEqualsInt(t, 1, len(errs))
+}
+
+func TestUpgradedStateHasNoDiff(t *testing.T) {
+	// the state of a deployment created by an older version of the provider does not have
+	// the optional attributes added since, which must not show a diff
+	state := &terraform.InstanceState{
+		ID: "594bf7ec-c8d2-4a0d-8477-553ed987aa48",
+		Attributes: map[string]string{
+			"catalog_item_name": "CentOS 7.0 x64",
+			"wait_timeout":      "15",
+		},
+	}
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"catalog_item_name": "CentOS 7.0 x64",
+	})
+	utils.AssertNilError(t, err)
+	diff, err := resourceVra7Deployment().Diff(state, terraform.NewResourceConfig(rawConfig))
+	utils.AssertNilError(t, err)
+	for _, key := range []string{"wait_timeout", "poll_interval", "cancel_on_timeout"} {
+		utils.AssertTrue(t, fmt.Sprintf("no diff of %s: %v", key, diff.Attributes[key]), diff.Attributes[key] == nil)
+	}
+}
+
+func TestImportDeployment(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	mockDeploymentID := "1ffcd9fe-d96e-47ce-9509-cdbae24862e3"
+	requestURL := c.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockRequestID, nil)
+	fake.register("GET "+requestURL, stringResponse(200, catalogRequestResponse))
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetRequestResourceViewAPI, mockRequestID), nil),
+		stringResponse(200, requestResourceViewResponse))
+	// the deployment id and name are not request ids
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockDeploymentID, nil),
+		stringResponse(400, requestNotFoundResponse))
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerRequests+"/Prativa_CentOs-86390713", nil),
+		stringResponse(400, requestNotFoundResponse))
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerResources+"/"+mockDeploymentID, nil),
+		stringResponse(200, `{"id":"`+mockDeploymentID+`","requestId":"`+mockRequestID+`"}`))
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerResources+"/Prativa_CentOs-86390713", nil),
+		stringResponse(404, requestNotFoundResponse))
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerResources, nil),
+		stringResponse(200, deploymentResourcesResponse))
+
+	for _, importID := range []string{mockRequestID, mockDeploymentID, "Prativa_CentOs-86390713"} {
+		mockResourceData := schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{})
+		mockResourceData.SetId(importID)
+		imported, err := resourceVra7DeploymentImport(mockResourceData, mockMeta(c))
+		utils.AssertNilError(t, err)
+		utils.AssertEqualsInt(t, 1, len(imported))
+
+		d := imported[0]
+		utils.AssertEqualsString(t, mockRequestID, d.Id())
+		utils.AssertEqualsString(t, "feaedf73-560c-4612-a573-41667e017691", d.Get("catalog_item_id").(string))
+		utils.AssertEqualsString(t, "Prativa_CentOs", d.Get("catalog_item_name").(string))
+		utils.AssertEqualsString(t, "b2470b94-cbca-43db-be37-803cca7b0f1a", d.Get("businessgroup_id").(string))
+		utils.AssertEqualsString(t, "Development", d.Get("businessgroup_name").(string))
+		utils.AssertEqualsString(t, "Prativa's deployment 1", d.Get("description").(string))
+		resourceConfiguration := d.Get("resource_configuration").(map[string]interface{})
+		utils.AssertEqualsString(t, "2", resourceConfiguration["vSphereVM1.cpu"].(string))
+		utils.AssertEqualsString(t, "2048", resourceConfiguration["vSphereVM1.memory"].(string))
+		// the computed properties are not imported
+		_, ok := resourceConfiguration["vSphereVM1.ip_address"]
+		utils.AssertFalse(t, "the ip address is not imported", ok)
+	}
+
+	// a deployment of another tenant is imported with the client of its tenant
+	financeClient, err := c.ForTenant("finance")
+	utils.AssertNilError(t, err)
+	financeClient.BearerToken = "Bearer mock-token"
+	financeClient.TokenExpires = time.Time{}
+	mockResourceData := schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{})
+	mockResourceData.SetId("finance/" + mockRequestID)
+	imported, err := resourceVra7DeploymentImport(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, imported[0].Id())
+	utils.AssertEqualsString(t, "finance", imported[0].Get("tenant").(string))
+
+	// the prefix of a name which is not a tenant is part of the name
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerResources, nil),
+		stringResponse(200, strings.Replace(deploymentResourcesResponse, "Prativa_CentOs-86390713", "web/Prativa_CentOs-86390713", -1)))
+	mockResourceData = schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{})
+	mockResourceData.SetId("web/Prativa_CentOs-86390713")
+	imported, err = resourceVra7DeploymentImport(mockResourceData, mockMeta(c))
+	utils.AssertNilError(t, err)
+	utils.AssertEqualsString(t, mockRequestID, imported[0].Id())
+	utils.AssertEqualsString(t, "", imported[0].Get("tenant").(string))
+	utils.AssertEqualsInt(t, 1, fake.callCount("GET "+c.BuildEncodedURL(sdk.Tenants+"/web", nil)))
+
+	// other errors fail the import
+	fake.register("GET "+requestURL, stringResponse(500, systemExceptionResponse))
+	mockResourceData = schema.TestResourceDataRaw(t, resourceVra7Deployment().Schema, map[string]interface{}{})
+	mockResourceData.SetId(mockRequestID)
+	_, err = resourceVra7DeploymentImport(mockResourceData, mockMeta(c))
+	utils.AssertNotNilError(t, err)
+}
+
+func TestDeleteFailedRequest(t *testing.T) {
+	fake := stubVRA(map[string]*sdk.APIResponse{})
+	c := mockClient(fake)
+
+	mockRequestID := "594bf7ec-c8d2-4a0d-8477-553ed987aa48"
+	mockDeploymentID := "1ffcd9fe-d96e-47ce-9509-cdbae24862e3"
+	mockActionID := "b7f1b1d4-5a4c-4b2b-9d0f-2f6d5e2b8a91"
+	mockDestroyRequestID := "a3a1f1a4-6b3c-4d7e-8f5a-9c2b1e0d4f62"
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetRequestResourceViewAPI, mockRequestID), nil),
+		stringResponse(200, requestResourceViewResponse))
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetResourceAPI, mockRequestID), nil),
+		stringResponse(200, `{"content":[{"id":"`+mockDeploymentID+`","name":"Prativa_CentOs-86390713",
+			"resourceTypeRef":{"id":"`+sdk.DeploymentResourceType+`"},"operations":[{"name":"`+sdk.Destroy+`","id":"`+mockActionID+`"}]}]}`))
+	fake.register("GET "+c.BuildEncodedURL(fmt.Sprintf(sdk.GetActionTemplateAPI, mockDeploymentID, mockActionID), nil),
+		stringResponse(200, `{"type":"com.vmware.vcac.catalog.domain.request.CatalogResourceRequest","data":{}}`))
+	postURL := c.BuildEncodedURL(fmt.Sprintf(sdk.PostActionTemplateAPI, mockDeploymentID, mockActionID), nil)
+	fake.register("POST "+postURL, &sdk.APIResponse{Status: "201 Created", StatusCode: 201,
+		Location: c.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockDestroyRequestID, nil)})
+	fake.register("GET "+c.BuildEncodedURL(sdk.ConsumerRequests+"/"+mockDestroyRequestID, nil),
+		stringResponse(200, failedRequestStatusResponse))
+
+	// the resource is destroyed through Apply, which sets up the timeouts
+	state := &terraform.InstanceState{
+		ID:         mockRequestID,
+		Attributes: map[string]string{"poll_interval": "1", "request_status": sdk.Successful},
+	}
+	diff := &terraform.InstanceDiff{Destroy: true}
+
+	// a destroy request which has failed is an error, the deployment is kept in the state
+	// with the status of its catalog request
+	state, err := resourceVra7Deployment().Apply(state, diff, mockMeta(c))
+	utils.AssertNotNilError(t, err)
+	utils.AssertContainsString(t, "CloneVM", err.Error())
+	utils.AssertEqualsString(t, mockRequestID, state.ID)
+	utils.AssertEqualsString(t, sdk.Successful, state.Attributes["request_status"])
+}
+
 // creates a mock request template from a request template template json file
 func GetMockRequestTemplate() *sdk.CatalogItemRequestTemplate {
 